@@ -0,0 +1,392 @@
+package serv
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidBindTarget error = errors.New("bind target must be a non-nil pointer to struct")
+
+	bindSources = []string{"path", "query", "form", "header", "cookie"}
+
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+
+	paramUnmarshalerType = reflect.TypeOf((*ParamUnmarshaler)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type ParamUnmarshaler interface {
+	UnmarshalParam(value string) error
+}
+
+type FieldError struct {
+	Field  string
+	Source string
+	Value  string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Source + ": " + e.Err.Error()
+	}
+	return e.Source + " " + e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+type BindErrors []*FieldError
+
+func (e BindErrors) Error() string {
+	list := make([]string, len(e))
+	for i, fe := range e {
+		list[i] = fe.Error()
+	}
+	return strings.Join(list, "; ")
+}
+
+// Is reports whether any of the field errors matches target, so that
+// errors.Is walks the list.
+func (e BindErrors) Is(target error) bool {
+	for _, fe := range e {
+		if errors.Is(fe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first field error that matches target.
+func (e BindErrors) As(target interface{}) bool {
+	for _, fe := range e {
+		if errors.As(fe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeError keeps the short message of a type mismatch and still
+// unwraps to the decoder error.
+type jsonTypeError struct {
+	*json.UnmarshalTypeError
+}
+
+func (e jsonTypeError) Error() string {
+	return fmt.Sprintf("cannot use %s as %s", e.Value, e.Type)
+}
+
+func (e jsonTypeError) Unwrap() error {
+	return e.UnmarshalTypeError
+}
+
+// Bind fills the struct pointed to by dst. A JSON body is decoded first,
+// then fields tagged with path, query, form, header or cookie are set from
+// the first listed source that has a value. Time fields use the layout tag
+// (RFC 3339 by default). Parse failures are collected into BindErrors.
+func (c *Context) Bind(dst interface{}) error {
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidBindTarget
+	}
+
+	var errs BindErrors
+
+	if err := c.bindBody(dst); err != nil {
+		if be, ok := err.(BindErrors); ok {
+			errs = append(errs, be...)
+		} else {
+			return err
+		}
+	}
+
+	c.bindStruct(v.Elem(), &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// BindValid binds dst and checks it with Validate.
+func (c *Context) BindValid(dst interface{}) error {
+
+	if err := c.Bind(dst); err != nil {
+		return err
+	}
+
+	return Validate(dst)
+}
+
+func (c *Context) bindBody(dst interface{}) error {
+
+	if c.req.Body == nil || c.req.ContentLength == 0 {
+		return nil
+	}
+
+	ct, _, _ := mime.ParseMediaType(c.GetContentType())
+
+	if ct == "application/x-www-form-urlencoded" || ct == "multipart/form-data" {
+		if err := c.parseForm(); err != nil {
+			if err == ErrBodyTooLarge {
+				return err
+			}
+			return BindErrors{{Source: "form", Err: err}}
+		}
+		return nil
+	}
+
+	fn, has := c.codecs.decoder(ct)
+	if !has {
+		return nil
+	}
+
+	if err := fn(c.Body(), dst); err != nil && err != io.EOF {
+		if c.bodyTooLarge {
+			return ErrBodyTooLarge
+		}
+		if ct == "application/json" || strings.HasSuffix(ct, "+json") {
+			return BindErrors{jsonFieldError(err)}
+		}
+		return BindErrors{{Source: "body", Err: err}}
+	}
+
+	return nil
+}
+
+func jsonFieldError(err error) *FieldError {
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		return &FieldError{Field: e.Field, Source: "json", Value: e.Value, Err: jsonTypeError{e}}
+	case *json.SyntaxError:
+		return &FieldError{Source: "json", Err: fmt.Errorf("%w at offset %d", e, e.Offset)}
+	}
+	return &FieldError{Source: "json", Err: err}
+}
+
+func (c *Context) bindValues(source string, name string) []string {
+	switch source {
+	case "path":
+		if v, has := c.params[name]; has {
+			return []string{v}
+		}
+	case "query":
+		return c.query()[name]
+	case "form":
+		c.parseForm()
+		return c.req.Form[name]
+	case "header":
+		return c.req.Header.Values(name)
+	case "cookie":
+		var res []string
+		for _, cookie := range c.req.Cookies() {
+			if cookie.Name == name {
+				res = append(res, cookie.Value)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// bindStruct reports whether any field was set, so that nil nested pointers
+// are only allocated when the request carries values for them.
+func (c *Context) bindStruct(v reflect.Value, errs *BindErrors) bool {
+
+	t := v.Type()
+	set := false
+
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		fv := v.Field(i)
+		tagged := false
+
+		for _, source := range bindSources {
+
+			name, has := sf.Tag.Lookup(source)
+			if !has || name == "-" {
+				continue
+			}
+
+			tagged = true
+
+			vals := c.bindValues(source, name)
+			if len(vals) == 0 {
+				continue
+			}
+
+			if err := setField(fv, vals, sf.Tag.Get("layout")); err != nil {
+				*errs = append(*errs, &FieldError{Field: name, Source: source, Value: vals[0], Err: err})
+			} else {
+				set = true
+			}
+
+			break
+		}
+
+		if tagged || !isNestedStruct(fv) {
+			continue
+		}
+
+		if fv.Kind() != reflect.Ptr {
+			set = c.bindStruct(fv, errs) || set
+		} else if !fv.IsNil() {
+			set = c.bindStruct(fv.Elem(), errs) || set
+		} else if fv.CanSet() {
+			nv := reflect.New(fv.Type().Elem())
+			if c.bindStruct(nv.Elem(), errs) {
+				fv.Set(nv)
+				set = true
+			}
+		}
+	}
+
+	return set
+}
+
+func isNestedStruct(v reflect.Value) bool {
+	return isNestedType(v.Type())
+}
+
+func isNestedType(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	pt := reflect.PtrTo(t)
+
+	return !pt.Implements(paramUnmarshalerType) && !pt.Implements(textUnmarshalerType)
+}
+
+func setField(v reflect.Value, vals []string, layout string) error {
+
+	if !v.CanSet() {
+		return errors.New("field is not settable")
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !v.Addr().Type().Implements(textUnmarshalerType) {
+		res := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, str := range vals {
+			if err := setValue(res.Index(i), str, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(res)
+		return nil
+	}
+
+	return setValue(v, vals[0], layout)
+}
+
+func setValue(v reflect.Value, str string, layout string) error {
+
+	if v.Kind() == reflect.Ptr {
+		nv := reflect.New(v.Type().Elem())
+		if err := setValue(nv.Elem(), str, layout); err != nil {
+			return err
+		}
+		v.Set(nv)
+		return nil
+	}
+
+	if v.CanAddr() {
+		switch u := v.Addr().Interface().(type) {
+		case ParamUnmarshaler:
+			return u.UnmarshalParam(str)
+		case encoding.TextUnmarshaler:
+			if v.Type() != timeType {
+				return u.UnmarshalText([]byte(str))
+			}
+		}
+	}
+
+	switch v.Type() {
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		tm, err := time.Parse(layout, str)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+
+	case durationType:
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(str)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(str))
+			return nil
+		}
+		return fmt.Errorf("unsupported type %s", v.Type())
+
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package serv
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindLevel int
+
+func (l *bindLevel) UnmarshalParam(value string) error {
+	switch value {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return ErrInvalidBindTarget
+	}
+	return nil
+}
+
+type bindPaging struct {
+	Page  int  `query:"page"`
+	Limit *int `query:"limit"`
+}
+
+type bindInput struct {
+	bindPaging
+	ID      int64         `path:"id"`
+	Token   string        `header:"X-Token"`
+	Session string        `cookie:"sid"`
+	Tags    []string      `query:"tag"`
+	Since   time.Time     `query:"since" layout:"2006-01-02"`
+	Timeout time.Duration `query:"timeout"`
+	Level   bindLevel     `query:"level"`
+	Name    string        `json:"name"`
+	Age     int           `json:"age"`
+}
+
+func TestBind(t *testing.T) {
+
+	s := New()
+
+	var in bindInput
+	var bindErr error
+
+	s.Register("POST", "/user/:id", func(c *Context) {
+		in = bindInput{}
+		bindErr = c.Bind(&in)
+	})
+
+	tB := func(url string, body string) {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Token", "secret")
+		req.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tB("/user/42?page=3&limit=10&tag=a&tag=b&since=2020-05-01&timeout=1m&level=high", `{"name":"Bob","age":33}`)
+
+	if bindErr != nil {
+		t.Fatal(bindErr)
+	}
+
+	if in.ID != 42 || in.Page != 3 || in.Limit == nil || *in.Limit != 10 || in.Token != "secret" || in.Session != "s1" {
+		t.Fatalf("bind failed: %+v", in)
+	}
+
+	if strings.Join(in.Tags, ",") != "a,b" || in.Since.Format("2006-01-02") != "2020-05-01" || in.Timeout != time.Minute || in.Level != 2 {
+		t.Fatalf("bind failed: %+v", in)
+	}
+
+	if in.Name != "Bob" || in.Age != 33 {
+		t.Fatalf("json bind failed: %+v", in)
+	}
+
+	tB("/user/x?page=y&level=mid", `{"name":"Bob","age":"old"}`)
+
+	errs, ok := bindErr.(BindErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("invalid bind errors: %v", bindErr)
+	}
+
+	fields := []string{}
+	for _, fe := range errs {
+		fields = append(fields, fe.Source+":"+fe.Field)
+	}
+
+	if strings.Join(fields, ",") != "json:age,query:page,path:id,query:level" {
+		t.Fatalf("invalid bind errors: %v", fields)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(bindErr, &typeErr) || typeErr.Field != "age" || !errors.Is(bindErr, ErrInvalidBindTarget) {
+		t.Fatalf("bind errors must unwrap: %v", bindErr)
+	}
+}
+
+func TestBodyJsonErrors(t *testing.T) {
+
+	s := New()
+
+	var bodyErr error
+
+	s.Register("POST", "/json", func(c *Context) {
+		var v map[string]interface{}
+		bodyErr = c.BodyJson(&v)
+	})
+
+	tJ := func(body string) {
+		req := httptest.NewRequest("POST", "/json", strings.NewReader(body))
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tJ("")
+
+	if !errors.Is(bodyErr, io.EOF) {
+		t.Fatalf("empty body must match io.EOF: %v", bodyErr)
+	}
+
+	tJ(`{"name":`)
+
+	if !errors.Is(bodyErr, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated body must match io.ErrUnexpectedEOF: %v", bodyErr)
+	}
+
+	tJ(`{"name" 1}`)
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(bodyErr, &syntaxErr) || bodyErr.Error() != "json: "+syntaxErr.Error()+" at offset 9" {
+		t.Fatalf("invalid syntax error: %v", bodyErr)
+	}
+}
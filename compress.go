@@ -0,0 +1,347 @@
+package serv
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+type CompressFunc func(w io.Writer, level int) (io.WriteCloser, error)
+
+// CompressOptions configures response compression. Types is an allow-list
+// of content type prefixes, Level is passed to the compressor as is.
+type CompressOptions struct {
+	MinSize int
+	Level   int
+	Types   []string
+}
+
+func DefaultCompressOptions() *CompressOptions {
+	return &CompressOptions{
+		MinSize: 1024,
+		Level:   -1,
+		Types: []string{
+			"text/",
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"application/problem+json",
+			"application/x-ndjson",
+			"image/svg+xml",
+		},
+	}
+}
+
+var precompressedExt = map[string]string{
+	"gzip": ".gz",
+	"br":   ".br",
+}
+
+type compressor struct {
+	name string
+	fn   CompressFunc
+}
+
+type compression struct {
+	opts          *CompressOptions
+	compressors   []compressor
+	precompressed bool
+}
+
+func newCompression() *compression {
+	return &compression{
+		compressors: []compressor{
+			{name: "gzip", fn: func(w io.Writer, level int) (io.WriteCloser, error) {
+				return gzip.NewWriterLevel(w, level)
+			}},
+			{name: "deflate", fn: func(w io.Writer, level int) (io.WriteCloser, error) {
+				return flate.NewWriter(w, level)
+			}},
+		},
+	}
+}
+
+// register puts a new compressor in front so that custom encoders (e.g. br)
+// are preferred over the built-in ones when the client accepts several.
+func (cm *compression) register(name string, fn CompressFunc) {
+	for i, c := range cm.compressors {
+		if c.name == name {
+			cm.compressors[i].fn = fn
+			return
+		}
+	}
+	cm.compressors = append([]compressor{{name: name, fn: fn}}, cm.compressors...)
+}
+
+func (cm *compression) choose(acceptEncoding string, allowed func(name string) bool) string {
+
+	items := parseAccept(acceptEncoding)
+
+	res := ""
+	best := 0.0
+
+	for _, c := range cm.compressors {
+		if allowed != nil && !allowed(c.name) {
+			continue
+		}
+		if q := acceptQuality(items, c.name); q > best {
+			res, best = c.name, q
+		}
+	}
+
+	return res
+}
+
+func (cm *compression) wrap(rw http.ResponseWriter, req *http.Request) *compressWriter {
+
+	if cm.opts == nil || req.Header.Get("Range") != "" {
+		return nil
+	}
+
+	name := cm.choose(req.Header.Get("Accept-Encoding"), nil)
+	if name == "" {
+		return nil
+	}
+
+	for _, c := range cm.compressors {
+		if c.name == name {
+			return &compressWriter{ResponseWriter: rw, opts: cm.opts, name: name, fn: c.fn}
+		}
+	}
+
+	return nil
+}
+
+func typeAllowed(types []string, ct string) bool {
+	ct = strings.ToLower(ct)
+	for _, t := range types {
+		if strings.HasPrefix(ct, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the first MinSize bytes to decide whether the
+// response is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *CompressOptions
+	name     string
+	fn       CompressFunc
+	status   int
+	buf      []byte
+	cw       io.WriteCloser
+	decided  bool
+	hijacked bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.opts.MinSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if w.cw != nil {
+		return w.cw.Write(p)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) eligible(h http.Header) bool {
+
+	if w.status != 0 && (w.status < 200 || w.status == 204 || w.status == 206 || w.status == 304) {
+		return false
+	}
+
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		if len(w.buf) == 0 {
+			return false
+		}
+		ct = http.DetectContentType(w.buf)
+		h.Set("Content-Type", ct)
+	}
+
+	if !typeAllowed(w.opts.Types, ct) {
+		return false
+	}
+
+	h.Add("Vary", "Accept-Encoding")
+
+	return len(w.buf) >= w.opts.MinSize
+}
+
+func (w *compressWriter) decide() error {
+
+	w.decided = true
+
+	h := w.ResponseWriter.Header()
+
+	if w.eligible(h) {
+		cw, err := w.fn(w.ResponseWriter, w.opts.Level)
+		if err != nil {
+			return err
+		}
+		w.cw = cw
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.name)
+
+		// The compressed body is another representation, so a strong
+		// validator of the identity body must not be reused for it.
+		if tag := h.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+			h.Set("ETag", "W/"+tag)
+		}
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+
+	if w.cw != nil {
+		_, err = w.cw.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+func (w *compressWriter) Flush() {
+
+	if w.hijacked {
+		return
+	}
+
+	if !w.decided {
+		w.decide()
+	}
+
+	if f, ok := w.cw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+
+	w.decided = true
+	w.hijacked = true
+
+	return hj.Hijack()
+}
+
+func (w *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *compressWriter) Close() error {
+
+	if w.hijacked {
+		return nil
+	}
+
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+
+	if w.cw != nil {
+		return w.cw.Close()
+	}
+
+	return nil
+}
+
+// servePrecompressed serves name+".gz" (or another known sibling) with the
+// matching Content-Encoding when the client accepts it.
+func (cm *compression) servePrecompressed(rw http.ResponseWriter, req *http.Request, fs http.FileSystem, name string) bool {
+
+	if !cm.precompressed || name == "" || strings.HasSuffix(name, "/") || req.Header.Get("Accept-Encoding") == "" {
+		return false
+	}
+
+	enc := cm.choose(req.Header.Get("Accept-Encoding"), func(name string) bool {
+		_, has := precompressedExt[name]
+		return has
+	})
+
+	if enc == "" {
+		return false
+	}
+
+	f, err := fs.Open(name + precompressedExt[enc])
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		return false
+	}
+
+	ct := mime.TypeByExtension(path.Ext(name))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+
+	h := rw.Header()
+	h.Set("Content-Type", ct)
+	h.Set("Content-Encoding", enc)
+	h.Add("Vary", "Accept-Encoding")
+
+	http.ServeContent(rw, req, name, st.ModTime(), f)
+
+	return true
+}
@@ -0,0 +1,180 @@
+package serv
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "serv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	big := strings.Repeat("compress me ", 200)
+
+	ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte(big), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("precompressed"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "logo.png"), []byte("\x89PNG\r\n\x1a\n"+big), 0644)
+
+	s := New()
+	s.SetCompression(DefaultCompressOptions())
+	s.Static("/static", dir)
+
+	s.Register("GET", "/big", func(c *Context) {
+		c.SetContentType("text/plain; charset=utf-8")
+		c.WriteString(big)
+	})
+
+	s.Register("GET", "/small", func(c *Context) {
+		c.SetContentType("text/plain; charset=utf-8")
+		c.WriteString("small")
+	})
+
+	s.Register("GET", "/encoded", func(c *Context) {
+		c.SetContentType("text/plain; charset=utf-8")
+		c.SetHeader("Content-Encoding", "identity")
+		c.WriteString(big)
+	})
+
+	tC := func(url string, accept string, enc string, body string) {
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Accept-Encoding", accept)
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != 200 || rw.Header().Get("Content-Encoding") != enc {
+			t.Fatalf("%s %s returns %d encoding %q", url, accept, rw.Code, rw.Header().Get("Content-Encoding"))
+		}
+
+		data := rw.Body.Bytes()
+
+		if enc == "gzip" && body != "precompressed" {
+			gr, err := gzip.NewReader(rw.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ = ioutil.ReadAll(gr)
+
+			if !strings.Contains(rw.Header().Get("Vary"), "Accept-Encoding") {
+				t.Fatalf("%s: Vary not set", url)
+			}
+		}
+
+		if body != "" && string(data) != body {
+			t.Fatalf("%s %s returns invalid body", url, accept)
+		}
+	}
+
+	tC("/big", "gzip, deflate", "gzip", big)
+	tC("/big", "deflate;q=0.5, gzip;q=0.1", "deflate", "")
+	tC("/big", "br", "", big)
+	tC("/big", "", "", big)
+	tC("/small", "gzip", "", "small")
+	tC("/encoded", "gzip", "identity", big)
+	tC("/static/app.js", "gzip", "gzip", big)
+	tC("/static/logo.png", "gzip", "", "")
+
+	s.StaticFS("/etag", os.DirFS(dir)).SetETag(true)
+
+	tE := func(accept string, enc string) string {
+
+		req := httptest.NewRequest("GET", "/etag/app.js", nil)
+		req.Header.Set("Accept-Encoding", accept)
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != 200 || rw.Header().Get("Content-Encoding") != enc {
+			t.Fatalf("/etag/app.js %s returns %d encoding %q", accept, rw.Code, rw.Header().Get("Content-Encoding"))
+		}
+
+		return rw.Header().Get("ETag")
+	}
+
+	strong := tE("identity", "")
+	if weak := tE("gzip", "gzip"); strings.HasPrefix(strong, "W/") || weak != "W/"+strong {
+		t.Fatalf("compressed body must have a weak ETag: %q %q", strong, weak)
+	}
+
+	req := httptest.NewRequest("GET", "/etag/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", "W/"+strong)
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, req)
+
+	if rw.Code != 304 {
+		t.Fatalf("weak ETag must revalidate: %d", rw.Code)
+	}
+
+	s.SetPrecompressed(true)
+
+	tC("/static/app.js", "gzip", "gzip", "precompressed")
+	tC("/static/app.js", "identity", "", big)
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed   []string
+	hijacked bool
+}
+
+func (r *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func (r *pushRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, errors.New("test hijack")
+}
+
+func TestCompressPassThrough(t *testing.T) {
+
+	s := New()
+	s.SetCompression(DefaultCompressOptions())
+
+	var pushErr error
+
+	s.Register("GET", "/push", func(c *Context) {
+		pushErr = c.rw.Push("/static/app.js", nil)
+		c.WriteString("OK")
+	})
+
+	s.Register("GET", "/hijack", func(c *Context) {
+		c.rw.Hijack()
+	})
+
+	tR := func(url string) *pushRecorder {
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		rw := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		s.router.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	if rw := tR("/push"); pushErr != nil || len(rw.pushed) != 1 || rw.pushed[0] != "/static/app.js" {
+		t.Fatalf("push not passed through: %v %v", pushErr, rw.pushed)
+	}
+
+	if rw := tR("/hijack"); !rw.hijacked {
+		t.Fatal("hijack not passed through")
+	}
+}
@@ -1,8 +1,10 @@
 package serv
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"html/template"
 	"io"
 	"mime/multipart"
 	"net"
@@ -13,14 +15,23 @@ import (
 	"github.com/wmentor/tt"
 )
 
+const defaultFormMemory = 32 << 20
+
 type Context struct {
-	rw           http.ResponseWriter
-	req          *http.Request
-	params       Params
-	qw           Query
-	statusCode   int
-	errorHandler ErrorHandler
-	tt           *tt.TT
+	rw             *responseWriter
+	req            *http.Request
+	params         Params
+	qw             Query
+	errorHandler   ErrorHandler
+	templates      *templates
+	nonce          string
+	trustedProxies []*net.IPNet
+	blockHandler   BlockHandler
+	body           io.ReadCloser
+	formMemory     int64
+	bodyTooLarge   bool
+	codecs         *codecs
+	values         map[string]interface{}
 }
 
 func (c *Context) StandardError(code int) {
@@ -42,9 +53,7 @@ func (c *Context) Write(data []byte) {
 }
 
 func (c *Context) WriteJson(v interface{}) {
-
-	encoder := json.NewEncoder(c.rw)
-	if err := encoder.Encode(v); err != nil {
+	if err := encodeJSON(c.rw, v); err != nil {
 		c.StandardError(500)
 	}
 }
@@ -54,24 +63,24 @@ func (c *Context) WriteString(txt string) {
 }
 
 func (c *Context) WriteRedirect(dest string) {
-	if c.statusCode == 0 {
-		c.statusCode = 302
+	if !c.rw.written {
 		http.Redirect(c.rw, c.req, dest, 302)
 	}
 }
 
 func (c *Context) WritePermanentRedirect(dest string) {
-	if c.statusCode == 0 {
-		c.statusCode = 301
+	if !c.rw.written {
 		http.Redirect(c.rw, c.req, dest, 301)
 	}
 }
 
 func (c *Context) WriteHeader(code int) {
-	if c.statusCode == 0 {
-		c.statusCode = code
-		c.rw.WriteHeader(code)
-	}
+	c.rw.WriteHeader(code)
+}
+
+// StatusCode returns the status sent to the client or 0 if nothing was written yet.
+func (c *Context) StatusCode() int {
+	return c.rw.status
 }
 
 func (c *Context) SetHeader(key, value string) {
@@ -90,6 +99,10 @@ func (c *Context) SetContentType(value string) {
 	c.SetHeader("Content-Type", value)
 }
 
+func (c *Context) Params() ParamValues {
+	return newParamValues(c.params)
+}
+
 func (c *Context) Param(name string) string {
 	return c.params.GetString(name)
 }
@@ -117,6 +130,10 @@ func (c *Context) query() Query {
 	return c.qw
 }
 
+func (c *Context) QueryValues() QueryValues {
+	return newQueryValues(c.query())
+}
+
 func (c *Context) Query(name string) string {
 	return c.query().GetString(name)
 }
@@ -141,38 +158,128 @@ func (c *Context) HasQueryParam(name string) bool {
 	return c.query().Has(name)
 }
 
+type limitedBody struct {
+	c     *Context
+	rc    io.ReadCloser
+	limit int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+
+	if b.c.req.ContentLength > b.limit {
+		b.c.bodyTooLarge = true
+		return 0, ErrBodyTooLarge
+	}
+
+	n, err := b.rc.Read(p)
+	if err != nil && errors.As(err, new(*http.MaxBytesError)) {
+		b.c.bodyTooLarge = true
+		err = ErrBodyTooLarge
+	}
+
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+// limitBody caps the request body at size bytes, size <= 0 removes the limit.
+// Reading past the limit fails with ErrBodyTooLarge and, unless the handler
+// has already replied, the client gets 413.
+func (c *Context) limitBody(size int64) {
+
+	if c.body == nil {
+		return
+	}
+
+	if size > 0 {
+		c.req.Body = &limitedBody{c: c, rc: http.MaxBytesReader(c.rw, c.body, size), limit: size}
+	} else {
+		c.req.Body = c.body
+	}
+}
+
+// MaxBodySize overrides the global body limit for a route; size <= 0 disables it.
+func MaxBodySize(size int64) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			c.limitBody(size)
+			next(c)
+		}
+	}
+}
+
+func (c *Context) parseForm() error {
+
+	if c.req.MultipartForm != nil || c.req.Form != nil && !strings.HasPrefix(c.GetContentType(), "multipart/form-data") {
+		return nil
+	}
+
+	mem := c.formMemory
+	if mem <= 0 {
+		mem = defaultFormMemory
+	}
+
+	err := c.req.ParseMultipartForm(mem)
+	if err == http.ErrNotMultipart {
+		return nil
+	}
+
+	if c.bodyTooLarge {
+		return ErrBodyTooLarge
+	}
+
+	return err
+}
+
 func (c *Context) FormFile(name string) (multipart.File, *multipart.FileHeader, error) {
+
+	if err := c.parseForm(); err != nil {
+		return nil, nil, err
+	}
+
 	f, fh, err := c.req.FormFile(name)
 	return f, fh, err
 }
 
+// FormValues returns the parsed form, including query parameters.
+func (c *Context) FormValues() QueryValues {
+	c.parseForm()
+	if c.req.Form == nil {
+		c.req.ParseForm()
+	}
+	return newQueryValues(Query(c.req.Form))
+}
+
 func (c *Context) FormValue(name string) string {
+	c.parseForm()
 	return c.req.FormValue(name)
 }
 
 func (c *Context) FormValueInt(name string) int {
-	if res, err := strconv.Atoi(c.req.FormValue(name)); err == nil {
+	if res, err := strconv.Atoi(c.FormValue(name)); err == nil {
 		return res
 	}
 	return 0
 }
 
 func (c *Context) FormValueInt64(name string) int64 {
-	if res, err := strconv.ParseInt(c.req.FormValue(name), 10, 64); err == nil {
+	if res, err := strconv.ParseInt(c.FormValue(name), 10, 64); err == nil {
 		return res
 	}
 	return 0
 }
 
 func (c *Context) FormValueBool(name string) bool {
-	if res, err := strconv.ParseBool(c.req.FormValue(name)); err == nil {
+	if res, err := strconv.ParseBool(c.FormValue(name)); err == nil {
 		return res
 	}
 	return false
 }
 
 func (c *Context) FormValueFloat(name string) float64 {
-	if res, err := strconv.ParseFloat(c.req.FormValue(name), 64); err == nil {
+	if res, err := strconv.ParseFloat(c.FormValue(name), 64); err == nil {
 		return res
 	}
 	return 0
@@ -196,7 +303,13 @@ func (c *Context) BodyJson(res interface{}) error {
 
 	if m == "POST" || m == "PUT" {
 		decoder := json.NewDecoder(c.Body())
-		return decoder.Decode(res)
+		if err := decoder.Decode(res); err != nil {
+			if c.bodyTooLarge {
+				return ErrBodyTooLarge
+			}
+			return BindErrors{jsonFieldError(err)}
+		}
+		return nil
 	}
 
 	return errorInvalidRequestMethod
@@ -221,62 +334,90 @@ func (c *Context) SetCookie(cookie *http.Cookie) {
 	}
 }
 
-func (c *Context) Render(tmpl string, vars map[string]interface{}) {
+func (c *Context) CSPNonce() string {
+	return c.nonce
+}
+
+func (c *Context) makeVars(vars map[string]interface{}) map[string]interface{} {
 
-	v := c.tt.MakeVars()
+	v := c.templates.makeVars()
+
+	if c.nonce != "" {
+		v["cspNonce"] = c.nonce
+	}
 
 	for k, val := range vars {
-		v.Set(k, val)
+		v[k] = val
 	}
 
-	if res, err := c.tt.Render(tmpl, v); err == nil {
-		c.Write(res)
-	} else {
+	return v
+}
+
+// writeRendered writes a rendered template or reports err and replies 500.
+func (c *Context) writeRendered(res []byte, err error) {
+
+	if err != nil {
 		if c.errorHandler != nil {
 			c.errorHandler(err)
 		}
+		if !c.rw.written {
+			c.StandardError(500)
+		}
+		return
 	}
+
+	c.Write(res)
 }
 
-func (c *Context) RenderStr(tmpl string, vars map[string]interface{}) {
+// Render renders tmpl with the renderer registered for its extension.
+func (c *Context) Render(tmpl string, vars map[string]interface{}) {
+	c.writeRendered(c.templates.render(tmpl, c.makeVars(vars)))
+}
 
-	v := c.tt.MakeVars()
+// RenderLayout renders tmpl and then layout with the result in the body
+// variable. A jet layout prints it with {{ body | raw }}, an html/template
+// one with {{ .body }}. Jet extends/include and html/template define/block
+// work inside templates as usual.
+func (c *Context) RenderLayout(layout string, tmpl string, vars map[string]interface{}) {
 
-	for k, val := range vars {
-		v.Set(k, val)
+	res, err := c.templates.render(tmpl, c.makeVars(vars))
+	if err == nil {
+		v := c.makeVars(vars)
+		v["body"] = template.HTML(res)
+		res, err = c.templates.render(layout, v)
 	}
 
-	if res, err := c.tt.RenderString(tmpl, v); err == nil {
-		c.Write(res)
-	} else {
-		if c.errorHandler != nil {
-			c.errorHandler(err)
-		}
-	}
+	c.writeRendered(res, err)
 }
 
-func (c *Context) RemoteAddr() string {
+// RenderStr renders a jet template given as a string.
+func (c *Context) RenderStr(tmpl string, vars map[string]interface{}) {
+	c.writeRendered(tt.RenderString(tmpl, ttVars(c.makeVars(vars))))
+}
 
-	if v := c.GetHeader("X-Forwarded-For"); len(v) > 0 {
-		addrs := strings.Split(v, ",")
-		if addrs != nil && len(addrs) > 0 {
-			ip := strings.TrimSpace(addrs[0])
-			if len(ip) < 20 {
-				return ip
-			}
-		}
-	}
+// Context returns the request context. It is cancelled when the client goes
+// away or the route timeout expires.
+func (c *Context) Context() context.Context {
+	return c.req.Context()
+}
 
-	if ip := c.GetHeader("X-Real-Ip"); len(ip) > 0 && len(ip) < 20 {
-		return ip
+// Set stores a value for the rest of the request, e.g. to pass data from a middleware to a handler.
+func (c *Context) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = make(map[string]interface{})
 	}
+	c.values[key] = value
+}
 
-	if ip, _, err := net.SplitHostPort(c.req.RemoteAddr); err == nil {
-		if len(ip) < 20 {
-			return (ip)
-		}
-	}
+func (c *Context) Get(key string) (interface{}, bool) {
+	v, has := c.values[key]
+	return v, has
+}
 
+func (c *Context) RemoteAddr() string {
+	if ip := realIP(c.req, c.trustedProxies); ip != nil {
+		return ip.String()
+	}
 	return ""
 }
 
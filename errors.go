@@ -1,7 +1,10 @@
 package serv
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"strconv"
 )
 
 var (
@@ -10,6 +13,7 @@ var (
 	errorInvalidRequestMethod error = errors.New("invalid request method")
 
 	ErrServerAlreadyStarted error = errors.New("routerer already started")
+	ErrBodyTooLarge         error = errors.New("request body too large")
 )
 
 func init() {
@@ -20,9 +24,194 @@ func init() {
 		403: []byte("403 Forbidden"),
 		404: []byte("404 Status Not Found"),
 		405: []byte("405 Method Not Allowed"),
+		406: []byte("406 Not Acceptable"),
 		409: []byte("409 Conflict"),
+		413: []byte("413 Request Entity Too Large"),
+		415: []byte("415 Unsupported Media Type"),
+		422: []byte("422 Unprocessable Entity"),
+		426: []byte("426 Upgrade Required"),
 		429: []byte("429 Too Many Requests"),
 		500: []byte("500 Internal Server Error"),
+		503: []byte("503 Service Unavailable"),
+		504: []byte("504 Gateway Timeout"),
 	}
 
+	for code := 100; code < 600; code++ {
+		if _, has := errorCodes[code]; !has {
+			if text := http.StatusText(code); text != "" {
+				errorCodes[code] = []byte(strconv.Itoa(code) + " " + text)
+			}
+		}
+	}
+}
+
+// StatusError is an HTTPError with an application error code and optional
+// details. Handlers can return it or panic with it.
+type StatusError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func NewError(status int, code string, message string) *StatusError {
+	return &StatusError{Status: status, Code: code, Message: message}
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode())
+	}
+	return e.Message
+}
+
+func (e *StatusError) StatusCode() int {
+	if _, has := errorCodes[e.Status]; !has {
+		return 500
+	}
+	return e.Status
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e *StatusError) WithDetails(details interface{}) *StatusError {
+	res := *e
+	res.Details = details
+	return &res
+}
+
+func (e BindErrors) StatusCode() int {
+	return 400
+}
+
+func (e ValidationErrors) StatusCode() int {
+	return 422
+}
+
+type problem struct {
+	Type    string      `json:"type"`
+	Title   string      `json:"title"`
+	Status  int         `json:"status"`
+	Detail  string      `json:"detail,omitempty"`
+	Code    string      `json:"code,omitempty"`
+	Errors  interface{} `json:"errors,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"field":   e.Field,
+		"source":  e.Source,
+		"message": e.Err.Error(),
+	})
+}
+
+// preferText reports whether the client asked for text (e.g. a browser)
+// rather than JSON.
+func (c *Context) preferText() bool {
+
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return false
+	}
+
+	items := parseAccept(accept)
+
+	js := acceptQuality(items, "application/problem+json")
+	if q := acceptQuality(items, "application/json"); q > js {
+		js = q
+	}
+
+	txt := acceptQuality(items, "text/plain")
+	if q := acceptQuality(items, "text/html"); q > txt {
+		txt = q
+	}
+
+	return txt > js
+}
+
+func (c *Context) writeProblem(p *problem) {
+
+	c.SetHeader("Vary", "Accept")
+
+	if c.preferText() {
+		msg := string(errorCodes[p.Status])
+		if p.Detail != "" {
+			msg += "\n" + p.Detail
+		}
+		c.SetContentType("text/plain; charset=utf-8")
+		c.WriteHeader(p.Status)
+		c.WriteString(msg)
+		return
+	}
+
+	c.SetContentType("application/problem+json")
+	c.WriteHeader(p.Status)
+	json.NewEncoder(c.rw).Encode(p)
+}
+
+func newProblem(code int, detail string) *problem {
+	return &problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: detail,
+	}
+}
+
+// WriteError replies to the client according to err: 400 for BindErrors,
+// 422 for ValidationErrors, the status of an HTTPError, 413/415/406 for the
+// matching errors and 500 for the rest. Structured errors are written as
+// application/problem+json unless the client prefers text.
+func (c *Context) WriteError(err error) {
+
+	var se *StatusError
+	var he HTTPError
+
+	switch e := err.(type) {
+	case BindErrors:
+		p := newProblem(400, "request contains invalid values")
+		p.Errors = e
+		c.writeProblem(p)
+	case ValidationErrors:
+		p := newProblem(422, "request validation failed")
+		p.Errors = e
+		c.writeProblem(p)
+	default:
+		if errors.As(err, &se) {
+			if se.StatusCode() >= 500 && c.errorHandler != nil {
+				c.errorHandler(err)
+			}
+			p := newProblem(se.StatusCode(), se.Error())
+			p.Code = se.Code
+			p.Details = se.Details
+			c.writeProblem(p)
+			return
+		}
+
+		if errors.As(err, &he) {
+			if he.StatusCode() >= 500 && c.errorHandler != nil {
+				c.errorHandler(err)
+			}
+			c.writeProblem(newProblem(he.StatusCode(), he.Error()))
+			return
+		}
+
+		switch err {
+		case ErrBodyTooLarge:
+			c.StandardError(413)
+			return
+		case ErrUnsupportedMediaType:
+			c.StandardError(415)
+			return
+		case ErrNotAcceptable:
+			c.StandardError(406)
+			return
+		}
+
+		if c.errorHandler != nil {
+			c.errorHandler(err)
+		}
+
+		c.StandardError(500)
+	}
 }
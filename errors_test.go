@@ -0,0 +1,62 @@
+package serv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+
+	s := New()
+
+	notFound := NewError(404, "item_not_found", "item does not exist")
+
+	s.Register("GET", "/return", func(c *Context) {
+		c.WriteError(fmt.Errorf("load item: %w", notFound.WithDetails(map[string]int{"id": 7})))
+	})
+
+	s.Register("GET", "/panic", func(c *Context) {
+		panic(NewError(409, "locked", "item is locked"))
+	})
+
+	s.Register("GET", "/teapot", func(c *Context) {
+		c.StandardError(418)
+	})
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/return", nil))
+
+	var p struct {
+		Status  int            `json:"status"`
+		Code    string         `json:"code"`
+		Detail  string         `json:"detail"`
+		Details map[string]int `json:"details"`
+	}
+
+	if err := json.Unmarshal(rw.Body.Bytes(), &p); err != nil || rw.Code != 404 || rw.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("invalid problem: %d %q", rw.Code, rw.Body.String())
+	}
+
+	if p.Status != 404 || p.Code != "item_not_found" || p.Detail != "item does not exist" || p.Details["id"] != 7 {
+		t.Fatalf("invalid problem: %+v", p)
+	}
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, req)
+
+	if rw.Code != 409 || rw.Body.String() != "409 Conflict\nitem is locked" {
+		t.Fatalf("invalid text error: %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/teapot", nil))
+
+	if rw.Code != 418 || rw.Body.String() != "418 I'm a teapot" {
+		t.Fatalf("invalid standard error: %d %q", rw.Code, rw.Body.String())
+	}
+}
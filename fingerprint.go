@@ -0,0 +1,207 @@
+package serv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// assets maps files of a static handler to fingerprinted names like
+// app.3f2a1c9e.js. In reload mode the files are rehashed when they change,
+// the check runs at most once per interval.
+type assets struct {
+	mu       sync.RWMutex
+	fsys     fs.FS
+	manifest map[string]string
+	reverse  map[string]string
+	reload   bool
+	interval time.Duration
+	checked  time.Time
+	modTime  time.Time
+}
+
+func newAssets(fsys fs.FS) *assets {
+	a := &assets{fsys: fsys, interval: defaultReloadInterval}
+	a.scan()
+	return a
+}
+
+func fingerprintName(name string, sum []byte) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+}
+
+func (a *assets) scan() {
+
+	manifest := make(map[string]string)
+	reverse := make(map[string]string)
+
+	var modTime time.Time
+
+	fs.WalkDir(a.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+
+		f, err := a.fsys.Open(name)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return nil
+		}
+
+		fp := fingerprintName(name, h.Sum(nil))
+		manifest[name] = fp
+		reverse[fp] = name
+
+		return nil
+	})
+
+	a.mu.Lock()
+	a.manifest = manifest
+	a.reverse = reverse
+	a.modTime = modTime
+	a.checked = time.Now()
+	a.mu.Unlock()
+}
+
+func (a *assets) refresh() {
+
+	a.mu.RLock()
+	check := a.reload && time.Since(a.checked) >= a.interval
+	modTime := a.modTime
+	a.mu.RUnlock()
+
+	if !check {
+		return
+	}
+
+	a.mu.Lock()
+	a.checked = time.Now()
+	a.mu.Unlock()
+
+	if !fsModTime(a.fsys).Equal(modTime) {
+		a.scan()
+	}
+}
+
+func (a *assets) original(name string) (string, bool) {
+	a.refresh()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	orig, has := a.reverse[name]
+	return orig, has
+}
+
+func (a *assets) lookup(name string) (string, bool) {
+	a.refresh()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	fp, has := a.manifest[name]
+	return fp, has
+}
+
+func (a *assets) copyManifest() map[string]string {
+	a.refresh()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	res := make(map[string]string, len(a.manifest))
+	for k, v := range a.manifest {
+		res[k] = v
+	}
+	return res
+}
+
+// fsModTime returns the latest modification time of files in fsys.
+func fsModTime(fsys fs.FS) time.Time {
+
+	var res time.Time
+
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(res) {
+			res = info.ModTime()
+		}
+		return nil
+	})
+
+	return res
+}
+
+// SetFingerprint hashes every file under the handler and serves it also as
+// name.<hash>.ext with immutable caching. With reload the files are rehashed
+// when they change, which is meant for development.
+func (sh *StaticHandler) SetFingerprint(enable bool, reload bool) *StaticHandler {
+
+	if !enable {
+		sh.assets = nil
+		return sh
+	}
+
+	sh.assets = newAssets(sh.fsys)
+	sh.assets.reload = reload
+
+	return sh
+}
+
+// Asset returns the fingerprinted URL of name, which is either relative to
+// the handler ("app.js") or a full path ("/static/app.js").
+func (sh *StaticHandler) Asset(name string) (string, bool) {
+
+	if sh.assets == nil {
+		return "", false
+	}
+
+	if strings.HasPrefix(name, sh.prefix) {
+		name = name[len(sh.prefix):]
+	}
+
+	fp, has := sh.assets.lookup(strings.TrimPrefix(name, "/"))
+	if !has {
+		return "", false
+	}
+
+	return sh.prefix + fp, true
+}
+
+// Manifest maps file names to their fingerprinted names.
+func (sh *StaticHandler) Manifest() map[string]string {
+	if sh.assets == nil {
+		return map[string]string{}
+	}
+	return sh.assets.copyManifest()
+}
+
+func (sh *StaticHandler) WriteManifest(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sh.Manifest())
+}
+
+// Asset maps name to a fingerprinted URL of the first static handler that
+// knows it, or returns name unchanged. Templates call it as asset("app.js").
+func (s *Server) Asset(name string) string {
+	for _, sh := range s.router.staticHandlers {
+		if url, has := sh.Asset(name); has {
+			return url
+		}
+	}
+	return name
+}
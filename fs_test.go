@@ -0,0 +1,56 @@
+package serv
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"public/app.js":     {Data: []byte("console.log(1)")},
+		"robots.txt":        {Data: []byte("User-agent: *")},
+		"views/layout.jet":  {Data: []byte(`<p>{{ block body() }}{{ end }}</p>`)},
+		"views/page.jet":    {Data: []byte(`{{ extends "layout.jet" }}{{ block body() }}{{ include "part.jet" }}{{ end }}`)},
+		"views/part.jet":    {Data: []byte(`hi {{ name }}`)},
+		"views/raw.jet":     {Data: []byte(`{{ name | noescape }} {{ name | queryescape }}`)},
+		"views/page.gohtml": {Data: []byte(`<i>{{ .name }}</i>`)},
+	}
+
+	sub, _ := fsys.Sub("views")
+
+	s := New()
+	s.StaticFS("/static", fsys)
+	s.FileFS("/robots.txt", fsys, "robots.txt")
+	s.LoadTemplatesFS(sub)
+
+	if err := s.LoadHTMLTemplatesFS(sub, ".gohtml"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Register("GET", "/page/:kind", func(c *Context) {
+		c.Render("page."+c.Param("kind"), map[string]interface{}{"name": "<bob>"})
+	})
+
+	s.Register("GET", "/raw", func(c *Context) {
+		c.Render("raw.jet", map[string]interface{}{"name": "<b c>"})
+	})
+
+	tG := func(url string, code int, body string) {
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, httptest.NewRequest("GET", url, nil))
+
+		if rw.Code != code || (body != "" && rw.Body.String() != body) {
+			t.Fatalf("%s: %d %q", url, rw.Code, rw.Body.String())
+		}
+	}
+
+	tG("/static/public/app.js", 200, "console.log(1)")
+	tG("/static/public/none.js", 404, "")
+	tG("/robots.txt", 200, "User-agent: *")
+	tG("/page/jet", 200, "<p>hi &lt;bob&gt;</p>")
+	tG("/raw", 200, "<b c> %3Cb+c%3E")
+	tG("/page/gohtml", 200, "<i>&lt;bob&gt;</i>")
+}
@@ -1,6 +1,7 @@
 package serv
 
 import (
+	"io/fs"
 	"time"
 )
 
@@ -40,30 +41,146 @@ func SetLogger(l Logger) {
 	server.SetLogger(l)
 }
 
-func Static(prefix string, dir string) {
-	server.Static(prefix, dir)
+func SetTrustedProxies(cidrs ...string) error {
+	return server.SetTrustedProxies(cidrs...)
+}
+
+func SetDenyList(list *IPList) {
+	server.SetDenyList(list)
+}
+
+func SetAllowList(list *IPList) {
+	server.SetAllowList(list)
+}
+
+func SetBlockHandler(fn BlockHandler) {
+	server.SetBlockHandler(fn)
+}
+
+func SetMaxBodySize(size int64) {
+	server.SetMaxBodySize(size)
+}
+
+func SetMultipartMemory(size int64) {
+	server.SetMultipartMemory(size)
+}
+
+func RegisterEncoder(mime string, fn EncodeFunc) {
+	server.RegisterEncoder(mime, fn)
+}
+
+func RegisterDecoder(mime string, fn DecodeFunc) {
+	server.RegisterDecoder(mime, fn)
+}
+
+func SetCompression(opts *CompressOptions) {
+	server.SetCompression(opts)
+}
+
+func RegisterCompressor(name string, fn CompressFunc) {
+	server.RegisterCompressor(name, fn)
+}
+
+func SetPrecompressed(enable bool) {
+	server.SetPrecompressed(enable)
+}
+
+func Use(mws ...Middleware) {
+	server.Use(mws...)
+}
+
+func SetSecureHeaders(opts *SecureOptions) {
+	server.SetSecureHeaders(opts)
+}
+
+func Static(prefix string, dir string) *StaticHandler {
+	return server.Static(prefix, dir)
+}
+
+func StaticFS(prefix string, fsys fs.FS) *StaticHandler {
+	return server.StaticFS(prefix, fsys)
 }
 
 func File(path string, filename string) {
 	server.File(path, filename)
 }
 
-func Register(method string, path string, fn Handler) {
-	server.Register(method, path, fn)
+func FileFS(path string, fsys fs.FS, name string) {
+	server.FileFS(path, fsys, name)
+}
+
+func Register(method string, path string, fn Handler) *Route {
+	return server.Register(method, path, fn)
+}
+
+func RegisterAuth(method string, path string, fn Handler) *Route {
+	return server.RegisterAuth(method, path, fn)
+}
+
+func Group(prefix string, mws ...Middleware) *RouteGroup {
+	return server.Group(prefix, mws...)
+}
+
+func SetWebSocketOptions(opts *WSOptions) {
+	server.SetWebSocketOptions(opts)
 }
 
-func RegisterAuth(method string, path string, fn Handler) {
-	server.RegisterAuth(method, path, fn)
+func WebSocket(path string, fn WSHandler) *Route {
+	return server.WebSocket(path, fn)
 }
 
-func RegMethod(method string, fn interface{}) {
-	server.RegMethod(method, fn)
+func WebSocketAuth(path string, fn WSHandler) *Route {
+	return server.WebSocketAuth(path, fn)
 }
 
-func RegisterJsonRPC(url string) {
-	server.RegisterJsonRPC(url)
+func RegMethod(method string, fn interface{}, mws ...RPCMiddleware) *RPCMethod {
+	return server.RegMethod(method, fn, mws...)
+}
+
+func RegisterJsonRPC(url string) *RPC {
+	return server.RegisterJsonRPC(url)
+}
+
+func RegisterJsonRPCAuth(url string) *RPC {
+	return server.RegisterJsonRPCAuth(url)
 }
 
 func LoadTemplates(dir string) {
 	server.LoadTemplates(dir)
 }
+
+func LoadTemplatesFS(fsys fs.FS) {
+	server.LoadTemplatesFS(fsys)
+}
+
+func LoadHTMLTemplates(dir string, ext string) error {
+	return server.LoadHTMLTemplates(dir, ext)
+}
+
+func LoadHTMLTemplatesFS(fsys fs.FS, ext string) error {
+	return server.LoadHTMLTemplatesFS(fsys, ext)
+}
+
+func SetRenderer(ext string, r Renderer) {
+	server.SetRenderer(ext, r)
+}
+
+func SetTemplateReload(enable bool) {
+	server.SetTemplateReload(enable)
+}
+
+func AddTemplateFunc(name string, fn interface{}) {
+	server.AddTemplateFunc(name, fn)
+}
+
+func AddTemplateVar(name string, value interface{}) {
+	server.AddTemplateVar(name, value)
+}
+
+func ServeOpenAPI(url string, info APIInfo) *Route {
+	return server.ServeOpenAPI(url, info)
+}
+
+func ServeOpenRPC(url string, info APIInfo) *Route {
+	return server.ServeOpenRPC(url, info)
+}
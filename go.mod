@@ -1,10 +1,13 @@
 module github.com/wmentor/serv
 
-go 1.14
+go 1.19
 
 require (
+	github.com/CloudyKit/jet v2.1.2+incompatible
 	github.com/wmentor/jrpc v1.0.3
 	github.com/wmentor/latency v1.0.0
 	github.com/wmentor/tt v1.0.1
 	github.com/wmentor/uniq v1.0.0
 )
+
+require github.com/CloudyKit/fastprinter v0.0.0-20200109182630-33d98a066a53 // indirect
@@ -0,0 +1,76 @@
+package serv
+
+type RouteGroup struct {
+	server      *Server
+	prefix      string
+	middlewares []Middleware
+}
+
+func (s *Server) Group(prefix string, mws ...Middleware) *RouteGroup {
+	return &RouteGroup{server: s, prefix: prefix, middlewares: mws}
+}
+
+func (g *RouteGroup) Group(prefix string, mws ...Middleware) *RouteGroup {
+	list := make([]Middleware, 0, len(g.middlewares)+len(mws))
+	list = append(list, g.middlewares...)
+	list = append(list, mws...)
+	return &RouteGroup{server: g.server, prefix: joinPath(g.prefix, prefix), middlewares: list}
+}
+
+func (g *RouteGroup) Use(mws ...Middleware) {
+	g.middlewares = append(g.middlewares, mws...)
+}
+
+func (g *RouteGroup) wrap(fn Handler) Handler {
+	for i := len(g.middlewares) - 1; i >= 0; i-- {
+		fn = g.middlewares[i](fn)
+	}
+	return fn
+}
+
+func (g *RouteGroup) Register(method string, path string, fn Handler) *Route {
+	return g.server.Register(method, joinPath(g.prefix, path), g.wrap(fn))
+}
+
+func (g *RouteGroup) RegisterAuth(method string, path string, fn Handler) *Route {
+	route := g.server.Register(method, joinPath(g.prefix, path), g.wrap(g.server.authHandler(fn)))
+	route.auth = true
+	return route
+}
+
+func (g *RouteGroup) WebSocket(path string, fn WSHandler) *Route {
+	return g.Register("GET", path, g.server.wsHandler(fn))
+}
+
+func (g *RouteGroup) WebSocketAuth(path string, fn WSHandler) *Route {
+	return g.RegisterAuth("GET", path, g.server.wsHandler(fn))
+}
+
+// RegisterJsonRPC serves JSON-RPC at path within the group. Unlike
+// Server.RegisterJsonRPC, the endpoint only serves its own methods, not the
+// ones registered with Server.RegMethod.
+func (g *RouteGroup) RegisterJsonRPC(path string) *RPC {
+	rpc := newRPC(g.server, joinPath(g.prefix, path), nil)
+	g.Register("POST", path, rpc.Handle).Summary("JSON-RPC 2.0 endpoint").Tags("json-rpc")
+	return rpc
+}
+
+// RegisterJsonRPCAuth is RouteGroup.RegisterJsonRPC behind basic authentication.
+func (g *RouteGroup) RegisterJsonRPCAuth(path string) *RPC {
+	rpc := newRPC(g.server, joinPath(g.prefix, path), nil)
+	g.RegisterAuth("POST", path, rpc.Handle).Summary("JSON-RPC 2.0 endpoint").Tags("json-rpc")
+	return rpc
+}
+
+func joinPath(prefix string, path string) string {
+	if prefix == "" || prefix == "/" {
+		return path
+	}
+	if len(prefix) > 0 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+	return prefix + path
+}
@@ -0,0 +1,205 @@
+package serv
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+type BlockHandler func(ip string, c *Context)
+
+type ipNode struct {
+	childs [2]*ipNode
+	term   bool
+}
+
+// IPList is a set of CIDR ranges stored in a binary radix tree, one per address family.
+// It is safe for concurrent use and can be reloaded while the server is running.
+type IPList struct {
+	mu sync.RWMutex
+	v4 *ipNode
+	v6 *ipNode
+}
+
+func NewIPList(cidrs ...string) (*IPList, error) {
+
+	l := &IPList{v4: &ipNode{}, v6: &ipNode{}}
+
+	for _, str := range cidrs {
+		if err := l.Add(str); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func NewIPListFile(filename string) (*IPList, error) {
+
+	l, _ := NewIPList()
+
+	if err := l.LoadFile(filename); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func insertNet(root *ipNode, ip net.IP, ones int) {
+
+	for i := 0; i < ones; i++ {
+		if root.term {
+			return
+		}
+
+		bit := (ip[i/8] >> uint(7-i%8)) & 1
+
+		if root.childs[bit] == nil {
+			root.childs[bit] = &ipNode{}
+		}
+		root = root.childs[bit]
+	}
+
+	root.term = true
+	root.childs[0] = nil
+	root.childs[1] = nil
+}
+
+func (l *IPList) roots(ip net.IP) (*ipNode, net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return l.v4, ip4
+	}
+	return l.v6, ip.To16()
+}
+
+func (l *IPList) Add(cidr string) error {
+
+	n, err := parseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ones, bits := n.Mask.Size()
+
+	root, ip := l.roots(n.IP)
+	if len(ip) == net.IPv4len && bits == 8*net.IPv6len {
+		if ones -= 8 * (net.IPv6len - net.IPv4len); ones < 0 {
+			ones = 0
+		}
+	}
+
+	insertNet(root, ip, ones)
+
+	return nil
+}
+
+func (l *IPList) Contains(ip net.IP) bool {
+
+	if ip == nil {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	root, ip := l.roots(ip)
+
+	for i := 0; root != nil; i++ {
+		if root.term {
+			return true
+		}
+		if i == len(ip)*8 {
+			break
+		}
+		root = root.childs[(ip[i/8]>>uint(7-i%8))&1]
+	}
+
+	return false
+}
+
+func (l *IPList) ContainsString(addr string) bool {
+	return l.Contains(parseAddr(addr))
+}
+
+// Load replaces the list content with CIDRs read from r, one per line.
+// Empty lines and text after '#' are ignored. On error the list is left unchanged.
+func (l *IPList) Load(r io.Reader) error {
+
+	nl, _ := NewIPList()
+
+	br := bufio.NewScanner(r)
+
+	for br.Scan() {
+		line := br.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if err := nl.Add(line); err != nil {
+			return err
+		}
+	}
+
+	if err := br.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.v4, l.v6 = nl.v4, nl.v6
+	l.mu.Unlock()
+
+	return nil
+}
+
+func (l *IPList) LoadFile(filename string) error {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.Load(f)
+}
+
+func (c *Context) blockIP(ip string) {
+	if c.blockHandler != nil {
+		c.blockHandler(ip, c)
+	}
+	c.StandardError(403)
+}
+
+func AllowIPs(list *IPList) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			if ip := c.RemoteAddr(); list.ContainsString(ip) {
+				next(c)
+			} else {
+				c.blockIP(ip)
+			}
+		}
+	}
+}
+
+func DenyIPs(list *IPList) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			if ip := c.RemoteAddr(); list.ContainsString(ip) {
+				c.blockIP(ip)
+			} else {
+				next(c)
+			}
+		}
+	}
+}
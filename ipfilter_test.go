@@ -0,0 +1,91 @@
+package serv
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIPList(t *testing.T) {
+
+	l, err := NewIPList("10.0.0.0/8", "192.168.1.0/24", "2001:db8::/32", "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ip := range []string{"10.1.2.3", "192.168.1.200", "2001:db8::1", "8.8.8.8", "::ffff:10.0.0.1"} {
+		if !l.ContainsString(ip) {
+			t.Fatalf("%s not found", ip)
+		}
+	}
+
+	for _, ip := range []string{"11.0.0.1", "192.168.2.1", "2001:db9::1", "8.8.4.4", "", "bad"} {
+		if l.ContainsString(ip) {
+			t.Fatalf("%s found", ip)
+		}
+	}
+
+	if err := l.Load(strings.NewReader("# office\n172.16.0.0/12 # vpn\n\n::1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.ContainsString("10.1.2.3") || !l.ContainsString("172.20.0.1") || !l.ContainsString("::1") {
+		t.Fatal("Load failed")
+	}
+
+	if err := l.Load(strings.NewReader("1.2.3.4/40")); err == nil || !l.ContainsString("::1") {
+		t.Fatal("invalid list loaded")
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+
+	s := New()
+
+	office, _ := NewIPList("10.0.0.0/8")
+	abuse, _ := NewIPList("6.6.6.0/24")
+
+	blocked := ""
+	var logged *LogData
+
+	s.SetLogger(func(ld *LogData) { logged = ld })
+	s.SetDenyList(abuse)
+	s.SetBlockHandler(func(ip string, c *Context) { blocked = ip })
+	s.SetAuthCheck(func(login, passwd string) bool { return login == "admin" && passwd == "secret" })
+
+	s.Register("GET", "/", func(c *Context) { c.WriteString("OK") })
+
+	admin := s.Group("/admin", AllowIPs(office))
+	admin.RegisterAuth("GET", "/stat", func(c *Context) { c.WriteString("OK") })
+
+	tF := func(url string, remote string, auth bool, code int) {
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.RemoteAddr = remote
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code {
+			t.Fatalf("%s from %s returns %d wait %d", url, remote, rw.Code, code)
+		}
+	}
+
+	tF("/", "1.2.3.4:80", false, 200)
+	tF("/", "6.6.6.6:80", false, 403)
+
+	if blocked != "6.6.6.6" {
+		t.Fatal("block handler not called")
+	}
+
+	if logged == nil || logged.Addr != "6.6.6.6" || logged.StatusCode != 403 {
+		t.Fatal("denied request not logged")
+	}
+
+	tF("/admin/stat", "1.2.3.4:80", true, 403)
+	tF("/admin/stat", "10.0.0.1:80", false, 401)
+	tF("/admin/stat", "10.0.0.1:80", true, 200)
+}
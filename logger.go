@@ -14,6 +14,8 @@ type LogData struct {
 	Referer    string
 	UserAgent  string
 	UID        string
+	BytesIn    int64
+	BytesOut   int64
 }
 
 type Logger func(*LogData)
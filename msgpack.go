@@ -0,0 +1,148 @@
+package serv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// encodeMsgpack writes v in MessagePack. The value goes through encoding/json
+// first, so json tags and Marshalers apply as for application/json. Integers
+// keep the smallest MessagePack int form, other numbers become float64 and
+// map keys are sorted.
+func encodeMsgpack(w io.Writer, v interface{}) error {
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeMsgpack(bw, tree); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func writeMsgpack(w *bufio.Writer, v interface{}) error {
+
+	switch val := v.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+
+	case bool:
+		if val {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return writeMsgpackInt(w, i)
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return err
+		}
+		w.WriteByte(0xcb)
+		return binary.Write(w, binary.BigEndian, math.Float64bits(f))
+
+	case string:
+		writeMsgpackLen(w, len(val), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		_, err := w.WriteString(val)
+		return err
+
+	case []interface{}:
+		writeMsgpackLen(w, len(val), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range val {
+			if err := writeMsgpack(w, item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		writeMsgpackLen(w, len(val), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			if err := writeMsgpack(w, k); err != nil {
+				return err
+			}
+			if err := writeMsgpack(w, val[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("msgpack: unsupported type %T", v)
+}
+
+// writeMsgpackLen writes the header of a string, array or map of n items:
+// the fix form up to fixMax, then the 8 (when code8 != 0), 16 and 32 bit forms.
+func writeMsgpackLen(w *bufio.Writer, n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		w.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		w.WriteByte(code8)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(code16)
+		binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(code32)
+		binary.Write(w, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(w *bufio.Writer, i int64) error {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		return w.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		return w.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		w.WriteByte(0xcc)
+		return w.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		w.WriteByte(0xcd)
+		return binary.Write(w, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		w.WriteByte(0xce)
+		return binary.Write(w, binary.BigEndian, uint32(i))
+	case i >= 0:
+		w.WriteByte(0xcf)
+		return binary.Write(w, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		w.WriteByte(0xd0)
+		return w.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		w.WriteByte(0xd1)
+		return binary.Write(w, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		w.WriteByte(0xd2)
+		return binary.Write(w, binary.BigEndian, int32(i))
+	}
+	w.WriteByte(0xd3)
+	return binary.Write(w, binary.BigEndian, i)
+}
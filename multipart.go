@@ -0,0 +1,227 @@
+package serv
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrNotMultipart    error = errors.New("request is not multipart/form-data")
+	ErrPartTooLarge    error = errors.New("multipart part too large")
+	ErrTooManyParts    error = errors.New("too many multipart parts")
+	ErrInvalidFileName error = errors.New("invalid file name")
+	ErrMimeNotAllowed  error = errors.New("mime type not allowed")
+)
+
+// UploadOptions limits streamed multipart uploads. Zero values mean no limit.
+// AllowedTypes entries are matched against the sniffed content type of file
+// parts and may end with "/*", e.g. "image/*".
+type UploadOptions struct {
+	MaxPartSize  int64
+	MaxTotalSize int64
+	MaxParts     int
+	AllowedTypes []string
+}
+
+type UploadReader struct {
+	c     *Context
+	mr    *multipart.Reader
+	opts  UploadOptions
+	total int64
+	parts int
+	cur   *Upload
+}
+
+type Upload struct {
+	FormName    string
+	FileName    string
+	Header      textproto.MIMEHeader
+	ContentType string
+
+	ur   *UploadReader
+	br   *bufio.Reader
+	size int64
+}
+
+func (c *Context) UploadReader(opts *UploadOptions) (*UploadReader, error) {
+
+	ct, params, err := mime.ParseMediaType(c.GetContentType())
+	if err != nil || ct != "multipart/form-data" || params["boundary"] == "" {
+		return nil, ErrNotMultipart
+	}
+
+	ur := &UploadReader{c: c, mr: multipart.NewReader(c.Body(), params["boundary"])}
+	if opts != nil {
+		ur.opts = *opts
+	}
+
+	return ur, nil
+}
+
+// Next returns the next part of the form or io.EOF when there are no more parts.
+// The previous part is drained and must not be used after Next.
+func (ur *UploadReader) Next() (*Upload, error) {
+
+	if ur.cur != nil {
+		if _, err := io.Copy(ioutil.Discard, ur.cur); err != nil {
+			return nil, err
+		}
+		ur.cur = nil
+	}
+
+	part, err := ur.mr.NextPart()
+	if err != nil {
+		if ur.c.bodyTooLarge {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, err
+	}
+
+	if ur.parts++; ur.opts.MaxParts > 0 && ur.parts > ur.opts.MaxParts {
+		return nil, ErrTooManyParts
+	}
+
+	u := &Upload{
+		FormName: part.FormName(),
+		Header:   part.Header,
+		ur:       ur,
+		br:       bufio.NewReaderSize(part, 512),
+	}
+
+	if _, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition")); err == nil {
+		if name, has := params["filename"]; has {
+			if !validFileName(name) {
+				return nil, ErrInvalidFileName
+			}
+			u.FileName = name
+		}
+	}
+
+	if u.IsFile() {
+		head, err := u.br.Peek(512)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+
+		u.ContentType = http.DetectContentType(head)
+
+		if !mimeAllowed(u.ContentType, ur.opts.AllowedTypes) {
+			return nil, ErrMimeNotAllowed
+		}
+	}
+
+	ur.cur = u
+
+	return u, nil
+}
+
+// Each calls fn for every part until fn returns an error or the form ends.
+func (ur *UploadReader) Each(fn func(u *Upload) error) error {
+	for {
+		u, err := ur.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+}
+
+func (u *Upload) IsFile() bool {
+	return u.FileName != ""
+}
+
+func (u *Upload) Size() int64 {
+	return u.size
+}
+
+// Read never returns more than the part and total budgets allow. Once a
+// budget is used up, more data in the part fails with ErrPartTooLarge or
+// ErrBodyTooLarge.
+func (u *Upload) Read(p []byte) (int, error) {
+
+	limit := int64(-1)
+	var limitErr error
+
+	if max := u.ur.opts.MaxPartSize; max > 0 {
+		limit, limitErr = max-u.size, ErrPartTooLarge
+	}
+
+	if max := u.ur.opts.MaxTotalSize; max > 0 && (limit < 0 || max-u.ur.total < limit) {
+		limit, limitErr = max-u.ur.total, ErrBodyTooLarge
+	}
+
+	if limit == 0 {
+		var probe [1]byte
+		n, err := u.br.Read(probe[:])
+		if n > 0 {
+			return 0, limitErr
+		}
+		return 0, u.readErr(err)
+	}
+
+	if limit > 0 && int64(len(p)) > limit {
+		p = p[:limit]
+	}
+
+	n, err := u.br.Read(p)
+
+	u.size += int64(n)
+	u.ur.total += int64(n)
+
+	return n, u.readErr(err)
+}
+
+func (u *Upload) readErr(err error) error {
+	if err != nil && err != io.EOF && u.ur.c.bodyTooLarge {
+		return ErrBodyTooLarge
+	}
+	return err
+}
+
+func mimeAllowed(ct string, allowed []string) bool {
+
+	if len(allowed) == 0 {
+		return true
+	}
+
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+
+	for _, a := range allowed {
+		if a == ct || strings.HasSuffix(a, "/*") && strings.HasPrefix(ct, a[:len(a)-1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validFileName(name string) bool {
+
+	if name == "" || name == "." || name == ".." || len(name) > 255 || !utf8.ValidString(name) {
+		return false
+	}
+
+	for _, r := range name {
+		if r == '/' || r == '\\' || r == ':' || unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
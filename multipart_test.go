@@ -0,0 +1,152 @@
+package serv
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUploadReader(t *testing.T) {
+
+	s := New()
+
+	var opts *UploadOptions
+	var files map[string]string
+	var uploadErr error
+	var written int64
+
+	s.Register("POST", "/upload", func(c *Context) {
+
+		files = make(map[string]string)
+		written = 0
+
+		ur, err := c.UploadReader(opts)
+		if err != nil {
+			uploadErr = err
+			return
+		}
+
+		uploadErr = ur.Each(func(u *Upload) error {
+			buf := bytes.NewBuffer(nil)
+			n, err := io.Copy(buf, u)
+			written += n
+			if err != nil {
+				return err
+			}
+			files[u.FormName] = u.FileName + ":" + u.ContentType + ":" + buf.String()
+			return nil
+		})
+	})
+
+	type part struct {
+		name     string
+		filename string
+		data     string
+	}
+
+	tU := func(parts []part, wait error) {
+
+		buf := bytes.NewBuffer(nil)
+		mw := multipart.NewWriter(buf)
+
+		for _, p := range parts {
+			h := make(textproto.MIMEHeader)
+			if p.filename != "" {
+				h.Set("Content-Disposition", `form-data; name="`+p.name+`"; filename="`+p.filename+`"`)
+			} else {
+				h.Set("Content-Disposition", `form-data; name="`+p.name+`"`)
+			}
+			w, _ := mw.CreatePart(h)
+			io.WriteString(w, p.data)
+		}
+		mw.Close()
+
+		req := httptest.NewRequest("POST", "/upload", buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if uploadErr != wait {
+			t.Fatalf("upload returns %v wait %v", uploadErr, wait)
+		}
+	}
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 32)
+
+	tU([]part{{"title", "", "hello"}, {"img", "a.png", png}}, nil)
+
+	if files["title"] != "::hello" || files["img"] != "a.png:image/png:"+png {
+		t.Fatalf("invalid files: %v", files)
+	}
+
+	opts = &UploadOptions{AllowedTypes: []string{"image/*"}, MaxPartSize: 64, MaxParts: 2}
+
+	tU([]part{{"img", "a.png", png}}, nil)
+	tU([]part{{"doc", "a.txt", "plain text"}}, ErrMimeNotAllowed)
+	tU([]part{{"img", "../../etc/passwd", png}}, ErrInvalidFileName)
+	tU([]part{{"img", "a.png", png + strings.Repeat("\x00", 64)}}, ErrPartTooLarge)
+
+	if written != 64 {
+		t.Fatalf("%d bytes written past the part limit", written)
+	}
+
+	tU([]part{{"img", "a.png", png + strings.Repeat("\x00", 24)}}, nil)
+	tU([]part{{"a", "", "1"}, {"b", "", "2"}, {"c", "", "3"}}, ErrTooManyParts)
+
+	opts = &UploadOptions{MaxTotalSize: 100}
+
+	tU([]part{{"a", "", strings.Repeat("1", 60)}, {"b", "", strings.Repeat("2", 60)}}, ErrBodyTooLarge)
+
+	if written != 100 {
+		t.Fatalf("%d bytes written past the total limit", written)
+	}
+
+	tU([]part{{"a", "", strings.Repeat("1", 60)}, {"b", "", strings.Repeat("2", 40)}}, nil)
+
+	opts = &UploadOptions{MaxPartSize: 1000}
+
+	tU([]part{{"f", "big.bin", strings.Repeat("x", 5000)}}, ErrPartTooLarge)
+
+	if written != 1000 {
+		t.Fatalf("%d bytes written past the part limit", written)
+	}
+}
+
+func TestFormValueMultipartMemory(t *testing.T) {
+
+	s := New()
+	s.SetMultipartMemory(16)
+
+	var page int
+	var onDisk bool
+
+	s.Register("POST", "/form", func(c *Context) {
+		page = c.FormValueInt("page")
+		if fhs := c.req.MultipartForm.File["doc"]; len(fhs) == 1 {
+			f, _ := fhs[0].Open()
+			_, onDisk = f.(*os.File)
+			f.Close()
+		}
+	})
+
+	buf := bytes.NewBuffer(nil)
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("page", "3")
+	w, _ := mw.CreateFormFile("doc", "a.txt")
+	io.WriteString(w, strings.Repeat("x", 1024))
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/form", buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if page != 3 || !onDisk {
+		t.Fatalf("multipart memory limit ignored: page=%d onDisk=%v", page, onDisk)
+	}
+}
@@ -0,0 +1,371 @@
+package serv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedMediaType error = errors.New("unsupported media type")
+	ErrNotAcceptable        error = errors.New("not acceptable")
+)
+
+type EncodeFunc func(w io.Writer, v interface{}) error
+type DecodeFunc func(r io.Reader, v interface{}) error
+
+type CSVMarshaler interface {
+	MarshalCSV() ([][]string, error)
+}
+
+type encoder struct {
+	mime string
+	fn   EncodeFunc
+}
+
+type codecs struct {
+	encoders []encoder
+	decoders map[string]DecodeFunc
+}
+
+func newCodecs() *codecs {
+
+	cs := &codecs{decoders: make(map[string]DecodeFunc)}
+
+	cs.setEncoder("application/json", encodeJSON)
+	cs.setEncoder("application/xml", encodeXML)
+	cs.setEncoder("text/xml", encodeXML)
+	cs.setEncoder("text/csv", encodeCSV)
+	cs.setEncoder("application/msgpack", encodeMsgpack)
+	cs.setEncoder("application/x-msgpack", encodeMsgpack)
+
+	cs.decoders["application/json"] = decodeJSON
+	cs.decoders["application/xml"] = decodeXML
+	cs.decoders["text/xml"] = decodeXML
+
+	return cs
+}
+
+func (cs *codecs) setEncoder(mime string, fn EncodeFunc) {
+	for i, e := range cs.encoders {
+		if e.mime == mime {
+			cs.encoders[i].fn = fn
+			return
+		}
+	}
+	cs.encoders = append(cs.encoders, encoder{mime: mime, fn: fn})
+}
+
+func (cs *codecs) decoder(contentType string) (DecodeFunc, bool) {
+
+	ct, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	if fn, has := cs.decoders[ct]; has {
+		return fn, true
+	}
+
+	if strings.HasSuffix(ct, "+json") {
+		return decodeJSON, true
+	}
+
+	if strings.HasSuffix(ct, "+xml") {
+		return decodeXML, true
+	}
+
+	return nil, false
+}
+
+type acceptItem struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses Accept-like headers (Accept, Accept-Encoding) and
+// returns items ordered by preference.
+func parseAccept(header string) []acceptItem {
+
+	var res []acceptItem
+
+	for _, part := range strings.Split(header, ",") {
+
+		fields := strings.Split(part, ";")
+
+		item := acceptItem{value: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		if item.value == "" {
+			continue
+		}
+
+		for _, f := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(f), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					item.q = q
+				}
+			}
+		}
+
+		res = append(res, item)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].q > res[j].q
+	})
+
+	return res
+}
+
+// acceptQuality returns the quality of value given by the most specific matching item.
+func acceptQuality(items []acceptItem, value string) float64 {
+
+	best := -1
+	q := 0.0
+
+	for _, item := range items {
+
+		spec := -1
+
+		switch {
+		case item.value == value:
+			spec = 2
+		case item.value == "*" || item.value == "*/*":
+			spec = 0
+		case strings.HasSuffix(item.value, "/*") && strings.HasPrefix(value, item.value[:len(item.value)-1]):
+			spec = 1
+		}
+
+		if spec > best {
+			best, q = spec, item.q
+		}
+	}
+
+	return q
+}
+
+func (cs *codecs) negotiate(accept string) (encoder, bool) {
+
+	if len(cs.encoders) == 0 {
+		return encoder{}, false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return cs.encoders[0], true
+	}
+
+	items := parseAccept(accept)
+
+	var res encoder
+	best := 0.0
+
+	for _, e := range cs.encoders {
+		if q := acceptQuality(items, e.mime); q > best {
+			res, best = e, q
+		}
+	}
+
+	return res, best > 0
+}
+
+func mimeWithCharset(mime string) string {
+	if strings.HasPrefix(mime, "text/") || mime == "application/json" || mime == "application/xml" {
+		return mime + "; charset=utf-8"
+	}
+	return mime
+}
+
+// Negotiate writes data with the registered encoder that best matches the
+// Accept header of the request, or replies 406 when none is acceptable.
+func (c *Context) Negotiate(data interface{}) {
+
+	e, ok := c.codecs.negotiate(c.GetHeader("Accept"))
+	if !ok {
+		c.StandardError(406)
+		return
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	if err := e.fn(buf, data); err != nil {
+		if c.errorHandler != nil {
+			c.errorHandler(err)
+		}
+		c.StandardError(500)
+		return
+	}
+
+	c.SetHeader("Vary", "Accept")
+	c.SetContentType(mimeWithCharset(e.mime))
+	c.WriteHeader(200)
+	c.Write(buf.Bytes())
+}
+
+// Decode reads the request body with the decoder registered for its Content-Type.
+func (c *Context) Decode(dst interface{}) error {
+
+	fn, has := c.codecs.decoder(c.GetContentType())
+	if !has {
+		return ErrUnsupportedMediaType
+	}
+
+	if c.req.Body == nil {
+		return errors.New("empty body")
+	}
+
+	if err := fn(c.Body(), dst); err != nil {
+		if c.bodyTooLarge {
+			return ErrBodyTooLarge
+		}
+		return err
+	}
+
+	return nil
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSON(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// encodeXML writes v as an XML document. Slices and arrays, except []byte,
+// are wrapped in an <items> root element so the result stays well-formed.
+func encodeXML(w io.Writer, v interface{}) error {
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+
+		root := xml.StartElement{Name: xml.Name{Local: "items"}}
+
+		if err := enc.EncodeToken(root); err != nil {
+			return err
+		}
+
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+
+		if err := enc.EncodeToken(root.End()); err != nil {
+			return err
+		}
+
+		return enc.Flush()
+	}
+
+	return enc.Encode(v)
+}
+
+func decodeXML(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// encodeCSV supports [][]string, CSVMarshaler and slices of structs. Struct
+// columns are named by the csv tag or the field name.
+func encodeCSV(w io.Writer, v interface{}) error {
+
+	var rows [][]string
+
+	switch data := v.(type) {
+	case [][]string:
+		rows = data
+	case CSVMarshaler:
+		res, err := data.MarshalCSV()
+		if err != nil {
+			return err
+		}
+		rows = res
+	default:
+		res, err := structRows(v)
+		if err != nil {
+			return err
+		}
+		rows = res
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+func structRows(v interface{}) ([][]string, error) {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv: unsupported type %T", v)
+	}
+
+	et := rv.Type().Elem()
+	if et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+
+	if et.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: unsupported type %T", v)
+	}
+
+	var header []string
+	var fields []int
+
+	for i := 0; i < et.NumField(); i++ {
+		sf := et.Field(i)
+		name := sf.Tag.Get("csv")
+		if sf.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	rows := make([][]string, 0, rv.Len()+1)
+	rows = append(rows, header)
+
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+
+		row := make([]string, len(fields))
+		for j, idx := range fields {
+			row[j] = fmt.Sprint(item.Field(idx).Interface())
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,95 @@
+package serv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type negItem struct {
+	ID   int    `json:"id" xml:"id" csv:"id"`
+	Name string `json:"name" xml:"name" csv:"name"`
+}
+
+type negList struct {
+	Items []negItem `xml:"item"`
+}
+
+func TestNegotiate(t *testing.T) {
+
+	s := New()
+
+	s.RegisterEncoder("application/x-plain", func(w io.Writer, v interface{}) error {
+		_, err := fmt.Fprint(w, v)
+		return err
+	})
+
+	s.Register("GET", "/list", func(c *Context) {
+		c.Negotiate([]negItem{{1, "a"}, {2, "b"}})
+	})
+
+	s.Register("POST", "/list", func(c *Context) {
+		var l negList
+		if err := c.Decode(&l); err != nil {
+			c.WriteError(err)
+			return
+		}
+		c.Negotiate(l.Items)
+	})
+
+	tN := func(method string, accept string, ct string, body string, code int, wait string, waitCT string) {
+
+		req := httptest.NewRequest(method, "/list", strings.NewReader(body))
+		req.Header.Set("Accept", accept)
+		if ct != "" {
+			req.Header.Set("Content-Type", ct)
+		}
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code {
+			t.Fatalf("%s %s returns %d wait %d", method, accept, rw.Code, code)
+		}
+
+		if code == 200 && (rw.Body.String() != wait || rw.Header().Get("Content-Type") != waitCT) {
+			t.Fatalf("%s %s returns %s %q", method, accept, rw.Header().Get("Content-Type"), rw.Body.String())
+		}
+	}
+
+	tN("GET", "", "", "", 200, `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`+"\n", "application/json; charset=utf-8")
+	tN("GET", "text/csv;q=0.9, application/xml", "", "", 200,
+		`<?xml version="1.0" encoding="UTF-8"?>`+"\n<items><negItem><id>1</id><name>a</name></negItem><negItem><id>2</id><name>b</name></negItem></items>",
+		"application/xml; charset=utf-8")
+	tN("GET", "text/html, text/*;q=0.5, text/xml;q=0", "", "", 200, "id,name\n1,a\n2,b\n", "text/csv; charset=utf-8")
+	tN("GET", "application/x-plain, application/json;q=0.1", "", "", 200, "[{1 a} {2 b}]", "application/x-plain")
+	tN("GET", "application/msgpack", "", "", 200, "\x92\x82\xa2id\x01\xa4name\xa1a\x82\xa2id\x02\xa4name\xa1b", "application/msgpack")
+	tN("GET", "image/png, */*;q=0", "", "", 406, "", "")
+	tN("POST", "text/csv", "application/xml", "<negList><item><id>7</id><name>x</name></item></negList>", 200, "id,name\n7,x\n", "text/csv; charset=utf-8")
+	tN("POST", "text/csv", "application/yaml", "items: []", 415, "", "")
+}
+
+func TestMsgpack(t *testing.T) {
+
+	tM := func(v interface{}, wait string) {
+
+		buf := bytes.NewBuffer(nil)
+
+		if err := encodeMsgpack(buf, v); err != nil || buf.String() != wait {
+			t.Fatalf("msgpack %v returns %x wait %x", v, buf.String(), wait)
+		}
+	}
+
+	tM(nil, "\xc0")
+	tM(true, "\xc3")
+	tM(-5, "\xfb")
+	tM(200, "\xcc\xc8")
+	tM(-200, "\xd1\xff\x38")
+	tM(70000, "\xce\x00\x01\x11\x70")
+	tM(1.5, "\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00")
+	tM(strings.Repeat("x", 40), "\xd9\x28"+strings.Repeat("x", 40))
+	tM(map[string]int{"b": 2, "a": 1}, "\x82\xa1a\x01\xa1b\x02")
+}
@@ -0,0 +1,337 @@
+package serv
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// APIInfo is the info section of generated OpenAPI and OpenRPC documents.
+type APIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+var paramSources = []string{"path", "query", "header", "cookie"}
+
+func isParamField(sf reflect.StructField) bool {
+	for _, source := range bindSources {
+		if name, has := sf.Tag.Lookup(source); has && name != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// collectParams walks the bind tags of t like Context.Bind does, nested
+// structs without tags included.
+func (b *schemaBuilder) collectParams(t reflect.Type, params map[string]map[string]interface{}, form map[string]interface{}, formRequired *[]string) {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		rules := parseRules(sf.Tag.Get("validate"))
+		tagged := false
+
+		for _, source := range paramSources {
+
+			name, has := sf.Tag.Lookup(source)
+			if !has || name == "-" {
+				continue
+			}
+
+			tagged = true
+
+			schema := b.paramSchema(sf.Type)
+			required := applyRules(schema, sf.Type, rules) || source == "path"
+
+			params[source+":"+name] = map[string]interface{}{
+				"name":     name,
+				"in":       source,
+				"required": required,
+				"schema":   schema,
+			}
+
+			break
+		}
+
+		if name, has := sf.Tag.Lookup("form"); !tagged && has && name != "-" {
+			tagged = true
+			schema := b.paramSchema(sf.Type)
+			if applyRules(schema, sf.Type, rules) {
+				*formRequired = append(*formRequired, name)
+			}
+			form[name] = schema
+		}
+
+		if tagged || !isNestedType(sf.Type) {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		b.collectParams(ft, params, form, formRequired)
+	}
+}
+
+func hasValidation(t reflect.Type) bool {
+
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Tag.Get("validate") != "" || hasValidation(sf.Type) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func problemResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/problem+json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/Problem"},
+			},
+		},
+	}
+}
+
+func (b *schemaBuilder) operation(r *Route) (map[string]interface{}, bool) {
+
+	op := map[string]interface{}{}
+
+	if r.summary != "" {
+		op["summary"] = r.summary
+	}
+
+	if r.description != "" {
+		op["description"] = r.description
+	}
+
+	if len(r.tags) > 0 {
+		op["tags"] = r.tags
+	}
+
+	_, names := apiPath(r.path)
+
+	params := make(map[string]map[string]interface{})
+	form := make(map[string]interface{})
+	var formRequired []string
+
+	for _, name := range names {
+		params["path:"+name] = map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		}
+	}
+
+	responses := map[string]interface{}{}
+	usesProblem := false
+
+	if in := r.input; in != nil {
+
+		for in.Kind() == reflect.Ptr {
+			in = in.Elem()
+		}
+
+		if in.Kind() == reflect.Struct && in != timeType {
+			b.collectParams(in, params, form, &formRequired)
+
+			schema := b.object(in, func(sf reflect.StructField) bool {
+				return isParamField(sf) || !sf.Anonymous && sf.Tag.Get("json") == "" && isNestedType(sf.Type) && !hasJSONFields(sf.Type)
+			})
+
+			if props := schema["properties"].(map[string]interface{}); len(props) > 0 {
+				if len(form) == 0 {
+					op["requestBody"] = map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{"schema": schema},
+						},
+					}
+				}
+			}
+		} else {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": b.schema(in)},
+				},
+			}
+		}
+
+		if len(form) > 0 {
+			schema := map[string]interface{}{"type": "object", "properties": form}
+			if len(formRequired) > 0 {
+				schema["required"] = formRequired
+			}
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/x-www-form-urlencoded": map[string]interface{}{"schema": schema},
+					"multipart/form-data":               map[string]interface{}{"schema": schema},
+				},
+			}
+		}
+
+		responses["400"] = problemResponse("Bad Request")
+		usesProblem = true
+
+		if hasValidation(in) {
+			responses["422"] = problemResponse("Unprocessable Entity")
+		}
+	}
+
+	if len(params) > 0 {
+		keys := make([]string, 0, len(params))
+		for k := range params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		list := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			list = append(list, params[k])
+		}
+		op["parameters"] = list
+	}
+
+	if r.output != nil {
+		responses["200"] = map[string]interface{}{
+			"description": "OK",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": b.schema(r.output)},
+			},
+		}
+	} else {
+		responses["200"] = map[string]interface{}{"description": "OK"}
+	}
+
+	if r.auth {
+		op["security"] = []interface{}{map[string]interface{}{"basicAuth": []string{}}}
+		responses["401"] = map[string]interface{}{"description": "Unauthorized"}
+	}
+
+	op["responses"] = responses
+
+	return op, usesProblem
+}
+
+// hasJSONFields reports whether a nested struct holds body fields, i.e. it
+// is not only a group of bind-tagged parameters.
+func hasJSONFields(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || sf.Tag.Get("json") == "-" || isParamField(sf) {
+			continue
+		}
+		if isNestedType(sf.Type) && sf.Tag.Get("json") == "" && !hasJSONFields(sf.Type) {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+// OpenAPI returns an OpenAPI 3 document of the registered routes. Routes are
+// described by their Route metadata; path parameters come from the pattern.
+func (s *Server) OpenAPI(info APIInfo) map[string]interface{} {
+
+	b := newSchemaBuilder("#/components/schemas/")
+
+	paths := make(map[string]interface{})
+	usesProblem := false
+	usesAuth := false
+
+	for _, r := range s.routes {
+
+		if r.hidden {
+			continue
+		}
+
+		path, _ := apiPath(r.path)
+
+		item, has := paths[path].(map[string]interface{})
+		if !has {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+
+		op, problem := b.operation(r)
+		usesProblem = usesProblem || problem
+		usesAuth = usesAuth || r.auth
+
+		item[strings.ToLower(r.method)] = op
+	}
+
+	if usesProblem {
+		b.components["Problem"] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":   map[string]interface{}{"type": "string"},
+				"title":  map[string]interface{}{"type": "string"},
+				"status": map[string]interface{}{"type": "integer"},
+				"detail": map[string]interface{}{"type": "string"},
+				"code":   map[string]interface{}{"type": "string"},
+				"errors": map[string]interface{}{"type": "array", "items": map[string]interface{}{}},
+			},
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    info,
+		"paths":   paths,
+	}
+
+	components := map[string]interface{}{}
+
+	if len(b.components) > 0 {
+		components["schemas"] = b.components
+	}
+
+	if usesAuth {
+		components["securitySchemes"] = map[string]interface{}{
+			"basicAuth": map[string]interface{}{"type": "http", "scheme": "basic"},
+		}
+	}
+
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+
+	return doc
+}
+
+// ServeOpenAPI serves the OpenAPI document at url. It is built on every
+// request, so routes registered later are included.
+func (s *Server) ServeOpenAPI(url string, info APIInfo) *Route {
+	return s.Register("GET", url, func(c *Context) {
+		c.sendJSON(s.OpenAPI(info))
+	}).Hide()
+}
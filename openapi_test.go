@@ -0,0 +1,175 @@
+package serv
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type docAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type docUser struct {
+	ID      int         `json:"id"`
+	Name    string      `json:"name" validate:"required,min=2,max=32"`
+	Email   string      `json:"email,omitempty" validate:"omitempty,email"`
+	Role    string      `json:"role" validate:"oneof=admin user"`
+	Created time.Time   `json:"created"`
+	Address *docAddress `json:"address,omitempty"`
+}
+
+type docUpdateUser struct {
+	ID    int    `path:"id"`
+	Trace string `header:"X-Trace"`
+	Name  string `json:"name" validate:"required"`
+}
+
+type docListUsers struct {
+	Page  int           `query:"page" validate:"min=1"`
+	Since time.Duration `query:"since"`
+}
+
+func dig(v interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func TestOpenAPI(t *testing.T) {
+
+	s := New()
+
+	s.Register("GET", "/users", func(c *Context) {}).
+		Summary("List users").Tags("users").Input(docListUsers{}).Output([]docUser{})
+
+	s.RegisterAuth("PUT", "/users/:id", func(c *Context) {}).
+		Input(&docUpdateUser{}).Output(docUser{})
+
+	RegisterJSON(s.Group("/v2"), "POST", "/users", func(c *Context, in *docAddress) (docUser, error) {
+		return docUser{}, nil
+	})
+
+	s.Register("GET", "/files/*", func(c *Context) {})
+	s.Register("GET", "/internal", func(c *Context) {}).Hide()
+	s.ServeOpenAPI("/openapi.json", APIInfo{Title: "Users", Version: "1.0"})
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &doc); err != nil || rw.Code != 200 {
+		t.Fatalf("invalid document: %d %s", rw.Code, rw.Body.String())
+	}
+
+	paths := doc["paths"].(map[string]interface{})
+
+	for _, p := range []string{"/openapi.json", "/internal"} {
+		if _, has := paths[p]; has {
+			t.Fatalf("hidden route %s in document", p)
+		}
+	}
+
+	if dig(doc, "info", "title") != "Users" || dig(paths, "/users", "get", "summary") != "List users" {
+		t.Fatal("invalid info or summary")
+	}
+
+	params := dig(paths, "/users", "get", "parameters").([]interface{})
+	if len(params) != 2 || dig(params[0], "name") != "page" || dig(params[0], "schema", "minimum") != 1.0 || dig(params[1], "schema", "format") != "duration" {
+		t.Fatalf("invalid query parameters: %v", params)
+	}
+
+	if dig(paths, "/users", "get", "responses", "200", "content", "application/json", "schema", "items", "$ref") != "#/components/schemas/docUser" {
+		t.Fatal("invalid list response")
+	}
+
+	put := dig(paths, "/users/{id}", "put")
+	if put == nil || dig(put, "security") == nil || dig(put, "responses", "401") == nil || dig(put, "responses", "422") == nil {
+		t.Fatalf("invalid put operation: %v", put)
+	}
+
+	body := dig(put, "requestBody", "content", "application/json", "schema")
+	if dig(body, "properties", "name") == nil || dig(body, "properties", "ID") != nil || dig(body, "required").([]interface{})[0] != "name" {
+		t.Fatalf("invalid request body: %v", body)
+	}
+
+	if len(dig(put, "parameters").([]interface{})) != 2 {
+		t.Fatalf("invalid put parameters: %v", dig(put, "parameters"))
+	}
+
+	typed := dig(paths, "/v2/users", "post")
+	if dig(typed, "requestBody", "content", "application/json", "schema", "properties", "city") == nil ||
+		dig(typed, "responses", "200", "content", "application/json", "schema", "$ref") != "#/components/schemas/docUser" {
+		t.Fatalf("typed route without metadata: %v", typed)
+	}
+
+	if dig(paths, "/files/{path}", "get", "parameters") == nil {
+		t.Fatal("wildcard must be a path parameter")
+	}
+
+	user := dig(doc, "components", "schemas", "docUser")
+	if dig(user, "properties", "name", "minLength") != 2.0 || dig(user, "properties", "email", "format") != "email" ||
+		dig(user, "properties", "created", "format") != "date-time" || len(dig(user, "properties", "role", "enum").([]interface{})) != 2 {
+		t.Fatalf("invalid user schema: %v", user)
+	}
+
+	if dig(doc, "components", "schemas", "docAddress", "required") == nil || dig(doc, "components", "securitySchemes", "basicAuth") == nil {
+		t.Fatal("invalid components")
+	}
+}
+
+func TestOpenRPC(t *testing.T) {
+
+	s := New()
+
+	s.RegMethod("ping", func() string { return "pong" })
+
+	rpc := s.RegisterJsonRPC("/rpc")
+	rpc.RegMethod("user.get", func(c *Context, in struct {
+		ID int `json:"id" validate:"required"`
+	}) (*docUser, error) {
+		return nil, nil
+	}).Summary("Get user")
+
+	rpc.ServeOpenRPC("/rpc/openrpc.json", APIInfo{Title: "RPC", Version: "1.0"})
+
+	admin := s.RegisterJsonRPCAuth("/admin/rpc")
+	admin.RegMethod("drop", func() {})
+
+	if list := admin.OpenRPC(APIInfo{})["methods"].([]interface{}); len(list) != 1 || dig(list[0], "name") != "drop" {
+		t.Fatalf("admin document must list its own methods only: %v", list)
+	}
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/rpc/openrpc.json", nil))
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &doc); err != nil || rw.Code != 200 {
+		t.Fatalf("invalid document: %d %s", rw.Code, rw.Body.String())
+	}
+
+	methods := doc["methods"].([]interface{})
+	if len(methods) != 2 || dig(methods[0], "name") != "ping" || dig(methods[1], "summary") != "Get user" {
+		t.Fatalf("invalid methods: %v", methods)
+	}
+
+	get := methods[1]
+	if dig(get, "paramStructure") != "by-name" || dig(get, "result", "schema", "$ref") != "#/components/schemas/docUser" {
+		t.Fatalf("invalid method: %v", get)
+	}
+
+	param := dig(get, "params").([]interface{})[0]
+	if dig(param, "name") != "id" || dig(param, "required") != true {
+		t.Fatalf("invalid param: %v", param)
+	}
+
+	if dig(doc, "servers").([]interface{})[0].(map[string]interface{})["url"] != "/rpc" || dig(doc, "components", "schemas", "docUser") == nil {
+		t.Fatal("invalid servers or components")
+	}
+}
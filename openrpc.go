@@ -0,0 +1,155 @@
+package serv
+
+import (
+	"reflect"
+	"sort"
+)
+
+func (rpc *RPC) allDocs() map[string]*RPCMethod {
+
+	res := make(map[string]*RPCMethod)
+
+	if rpc.fallback != nil {
+		for k, v := range rpc.fallback.allDocs() {
+			res[k] = v
+		}
+	}
+
+	for k, v := range rpc.docs {
+		res[k] = v
+	}
+
+	return res
+}
+
+func (b *schemaBuilder) rpcMethod(m *RPCMethod) map[string]interface{} {
+
+	res := map[string]interface{}{"name": m.name}
+
+	if m.summary != "" {
+		res["summary"] = m.summary
+	}
+
+	if m.description != "" {
+		res["description"] = m.description
+	}
+
+	if len(m.tags) > 0 {
+		tags := make([]interface{}, len(m.tags))
+		for i, t := range m.tags {
+			tags[i] = map[string]interface{}{"name": t}
+		}
+		res["tags"] = tags
+	}
+
+	ft := m.fn
+	first := 0
+	if ft.NumIn() > 0 && ft.In(0) == contextType {
+		first = 1
+	}
+
+	params := []interface{}{}
+
+	if ft.NumIn() > first {
+
+		in := ft.In(first)
+		for in.Kind() == reflect.Ptr {
+			in = in.Elem()
+		}
+
+		if in.Kind() == reflect.Struct && in != timeType && isNestedType(in) {
+			obj := b.object(in, nil)
+			props := obj["properties"].(map[string]interface{})
+
+			required := map[string]bool{}
+			if list, ok := obj["required"].([]string); ok {
+				for _, name := range list {
+					required[name] = true
+				}
+			}
+
+			names := make([]string, 0, len(props))
+			for name := range props {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				params = append(params, map[string]interface{}{
+					"name":     name,
+					"required": required[name],
+					"schema":   props[name],
+				})
+			}
+
+			res["paramStructure"] = "by-name"
+		} else {
+			params = append(params, map[string]interface{}{
+				"name":     "params",
+				"required": true,
+				"schema":   b.schema(in),
+			})
+		}
+	}
+
+	res["params"] = params
+
+	if ft.NumOut() > 0 && !ft.Out(0).Implements(errorType) {
+		res["result"] = map[string]interface{}{
+			"name":   "result",
+			"schema": b.schema(ft.Out(0)),
+		}
+	}
+
+	return res
+}
+
+// OpenRPC returns an OpenRPC document of the methods available at this
+// endpoint. Only the endpoint of Server.RegisterJsonRPC lists the methods
+// registered on the Server as well.
+func (rpc *RPC) OpenRPC(info APIInfo) map[string]interface{} {
+
+	b := newSchemaBuilder("#/components/schemas/")
+
+	docs := rpc.allDocs()
+
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	methods := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		methods = append(methods, b.rpcMethod(docs[name]))
+	}
+
+	doc := map[string]interface{}{
+		"openrpc": "1.3.2",
+		"info":    info,
+		"methods": methods,
+	}
+
+	if rpc.url != "" {
+		doc["servers"] = []interface{}{map[string]interface{}{"name": "default", "url": rpc.url}}
+	}
+
+	if len(b.components) > 0 {
+		doc["components"] = map[string]interface{}{"schemas": b.components}
+	}
+
+	return doc
+}
+
+// ServeOpenRPC serves the OpenRPC document of this endpoint at url.
+func (rpc *RPC) ServeOpenRPC(url string, info APIInfo) *Route {
+	return rpc.server.Register("GET", url, func(c *Context) {
+		c.sendJSON(rpc.OpenRPC(info))
+	}).Hide()
+}
+
+// ServeOpenRPC serves the OpenRPC document of the methods registered with
+// RegMethod at url.
+func (s *Server) ServeOpenRPC(url string, info APIInfo) *Route {
+	return s.rpc.ServeOpenRPC(url, info)
+}
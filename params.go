@@ -61,3 +61,18 @@ func (p Params) GetBool(name string) bool {
 
 	return false
 }
+
+func (p Params) Lookup(name string) (string, bool) {
+	v, h := p[name]
+	return v, h
+}
+
+// ParamValues is the route parameters with the typed getters of ValueGetter.
+type ParamValues struct {
+	Params
+	ValueGetter
+}
+
+func newParamValues(p Params) ParamValues {
+	return ParamValues{Params: p, ValueGetter: ValueGetter{lookup: p.Lookup}}
+}
@@ -0,0 +1,123 @@
+package serv
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+func parseCIDR(str string) (*net.IPNet, error) {
+
+	str = strings.TrimSpace(str)
+
+	if !strings.Contains(str, "/") {
+		ip := net.ParseIP(str)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: str}
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, n, err := net.ParseCIDR(str)
+	return n, err
+}
+
+// parseAddr accepts "ip", "ip:port", "[ipv6]:port" and "ipv6%zone" forms.
+func parseAddr(str string) net.IP {
+
+	str = strings.TrimSpace(str)
+
+	if host, _, err := net.SplitHostPort(str); err == nil {
+		str = host
+	} else if strings.HasPrefix(str, "[") && strings.HasSuffix(str, "]") {
+		str = str[1 : len(str)-1]
+	}
+
+	if i := strings.IndexByte(str, '%'); i >= 0 {
+		str = str[:i]
+	}
+
+	return net.ParseIP(str)
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the for= nodes of an RFC 7239 Forwarded header in hop order.
+func forwardedFor(values []string) []string {
+
+	var res []string
+
+	for _, value := range values {
+		for _, elem := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					res = append(res, strings.Trim(kv[1], `"`))
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func forwardedChain(req *http.Request) []string {
+
+	if values := req.Header.Values("Forwarded"); len(values) > 0 {
+		return forwardedFor(values)
+	}
+
+	var res []string
+
+	for _, value := range req.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(value, ",") {
+			res = append(res, strings.TrimSpace(addr))
+		}
+	}
+
+	if len(res) == 0 {
+		if ip := req.Header.Get("X-Real-Ip"); ip != "" {
+			res = append(res, ip)
+		}
+	}
+
+	return res
+}
+
+// realIP walks the forwarding chain from right to left starting at the
+// connection peer and returns the first address not in the trusted list.
+// Proxy headers are ignored unless the peer itself is trusted.
+func realIP(req *http.Request, trusted []*net.IPNet) net.IP {
+
+	ip := parseAddr(req.RemoteAddr)
+	if ip == nil || !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	chain := forwardedChain(req)
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := parseAddr(chain[i])
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+
+	return ip
+}
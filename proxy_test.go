@@ -0,0 +1,57 @@
+package serv
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoteAddr(t *testing.T) {
+
+	s := New()
+
+	addr := ""
+
+	s.Register("GET", "/", func(c *Context) {
+		addr = c.RemoteAddr()
+	})
+
+	tR := func(remote string, headers map[string]string, wait string) {
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remote
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		addr = ""
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if addr != wait {
+			t.Fatalf("RemoteAddr for %s %v returns %s wait %s", remote, headers, addr, wait)
+		}
+	}
+
+	tR("1.2.3.4:1234", nil, "1.2.3.4")
+	tR("1.2.3.4:1234", map[string]string{"X-Forwarded-For": "5.6.7.8"}, "1.2.3.4")
+	tR("[2001:db8:aaaa:bbbb:cccc:dddd:eeee:1]:443", nil, "2001:db8:aaaa:bbbb:cccc:dddd:eeee:1")
+
+	if err := s.SetTrustedProxies("10.0.0.0/8", "::1", "bad"); err == nil {
+		t.Fatal("invalid CIDR accepted")
+	}
+
+	if err := s.SetTrustedProxies("10.0.0.0/8", "::1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tR("10.0.0.1:80", map[string]string{"X-Forwarded-For": "6.6.6.6, 5.6.7.8, 10.1.1.1"}, "5.6.7.8")
+	tR("10.0.0.1:80", map[string]string{"X-Forwarded-For": "10.2.2.2, 10.1.1.1"}, "10.2.2.2")
+	tR("10.0.0.1:80", map[string]string{"X-Forwarded-For": "unknown, 10.1.1.1"}, "10.1.1.1")
+	tR("10.0.0.1:80", map[string]string{"X-Real-Ip": "5.6.7.8"}, "5.6.7.8")
+	tR("1.2.3.4:80", map[string]string{"X-Real-Ip": "5.6.7.8"}, "1.2.3.4")
+	tR("[::1]:80", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1")
+	tR("10.0.0.1:80", map[string]string{
+		"Forwarded":       `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711";by=10.0.0.1`,
+		"X-Forwarded-For": "6.6.6.6",
+	}, "2001:db8:cafe::17")
+	tR("10.0.0.1:80", map[string]string{"Forwarded": `For="[2001:db8::2]", for=10.3.3.3`}, "2001:db8::2")
+}
@@ -84,3 +84,44 @@ func (p Query) GetFloat(name string) float64 {
 
 	return v
 }
+
+func (p Query) Lookup(name string) (string, bool) {
+	data, has := p[name]
+	if !has || len(data) == 0 {
+		return "", false
+	}
+	return data[0], true
+}
+
+func (p Query) GetAll(name string) []string {
+	return p[name]
+}
+
+func (p Query) GetInts(name string) ([]int, error) {
+	data, has := p[name]
+	if !has || len(data) == 0 {
+		return nil, ErrNoValue
+	}
+
+	res := make([]int, 0, len(data))
+
+	for _, str := range data {
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, nil
+}
+
+// QueryValues is a Query with the typed getters of ValueGetter.
+type QueryValues struct {
+	Query
+	ValueGetter
+}
+
+func newQueryValues(q Query) QueryValues {
+	return QueryValues{Query: q, ValueGetter: ValueGetter{lookup: q.Lookup}}
+}
@@ -2,6 +2,7 @@ package serv
 
 import (
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -45,3 +46,96 @@ func TestQuery(t *testing.T) {
 		t.Fatal("GetBool failed")
 	}
 }
+
+func TestQueryParse(t *testing.T) {
+
+	req := httptest.NewRequest("GET", "/?n=1&n=2&n=3&bad=x&zero=0&d=1m30s&ts=2020-05-01&id=6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil)
+
+	qw := newQueryValues(newQuery(req))
+
+	if v, err := qw.ParseInt("zero"); err != nil || v != 0 {
+		t.Fatal("ParseInt failed for zero")
+	}
+
+	if _, err := qw.ParseInt("missing"); err != ErrNoValue {
+		t.Fatal("ParseInt must return ErrNoValue")
+	}
+
+	if _, err := qw.ParseInt("bad"); err == nil || err == ErrNoValue {
+		t.Fatal("ParseInt must return parse error")
+	}
+
+	if qw.GetIntOr("bad", 7) != 7 || qw.GetIntOr("missing", 8) != 8 || qw.GetIntOr("zero", 9) != 0 {
+		t.Fatal("GetIntOr failed")
+	}
+
+	if list := qw.GetAll("n"); len(list) != 3 || list[2] != "3" {
+		t.Fatal("GetAll failed")
+	}
+
+	if list, err := qw.GetInts("n"); err != nil || len(list) != 3 || list[0]+list[1]+list[2] != 6 {
+		t.Fatal("GetInts failed")
+	}
+
+	if _, err := qw.GetInts("bad"); err == nil {
+		t.Fatal("GetInts must fail")
+	}
+
+	if d, err := qw.ParseDuration("d"); err != nil || d.Seconds() != 90 {
+		t.Fatal("ParseDuration failed")
+	}
+
+	if ts, err := qw.ParseTime("ts", "2006-01-02"); err != nil || ts.Month() != 5 {
+		t.Fatal("ParseTime failed")
+	}
+
+	if id, err := qw.ParseUUID("id"); err != nil || id.String() != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Fatal("ParseUUID failed")
+	}
+
+	if _, err := qw.ParseUUID("bad"); err != ErrInvalidUUID {
+		t.Fatal("ParseUUID must fail")
+	}
+
+	params := newParamValues(Params{"id": "{6BA7B810-9DAD-11D1-80B4-00C04FD430C8}", "n": "x"})
+
+	if id, err := params.ParseUUID("id"); err != nil || id.String() != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Fatal("Params.ParseUUID failed")
+	}
+
+	if _, err := params.ParseInt64("n"); err == nil || params.GetInt64Or("n", 5) != 5 {
+		t.Fatal("Params.ParseInt64 failed")
+	}
+}
+
+func TestFormValues(t *testing.T) {
+
+	s := New()
+
+	var page, size int
+	var tag string
+
+	s.Register("POST", "/form", func(c *Context) {
+		f := c.FormValues()
+		page = f.GetIntOr("page", 1)
+		size = f.GetIntOr("size", 20)
+		tag = f.GetStringOr("tag", "none")
+		c.WriteHeader(200)
+	})
+
+	tF := func(url string, body string, wPage int, wSize int, wTag string) {
+
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if page != wPage || size != wSize || tag != wTag {
+			t.Fatalf("%s %q: page=%d size=%d tag=%s", url, body, page, size, tag)
+		}
+	}
+
+	tF("/form", "", 1, 20, "none")
+	tF("/form?page=3", "size=50&tag=go", 3, 50, "go")
+	tF("/form?size=x", "page=2", 2, 20, "none")
+}
@@ -0,0 +1,151 @@
+package serv
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/CloudyKit/jet"
+	"github.com/wmentor/tt"
+)
+
+// Renderer renders the named template with vars.
+type Renderer interface {
+	Render(name string, vars map[string]interface{}) ([]byte, error)
+}
+
+type ttRenderer struct {
+	tt *tt.TT
+}
+
+// NewTTRenderer returns a Renderer using github.com/wmentor/tt (jet syntax).
+func NewTTRenderer(dirs ...string) Renderer {
+	return &ttRenderer{tt: tt.New(dirs...)}
+}
+
+func (r *ttRenderer) Render(name string, vars map[string]interface{}) ([]byte, error) {
+	return r.tt.Render(name, ttVars(vars))
+}
+
+// fsLoader lets jet read templates from an fs.FS, so embedded templates are
+// rendered from memory.
+type fsLoader struct {
+	fsys fs.FS
+}
+
+func (l *fsLoader) path(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (l *fsLoader) Open(name string) (io.ReadCloser, error) {
+	return l.fsys.Open(l.path(name))
+}
+
+func (l *fsLoader) Exists(name string) (string, bool) {
+	if st, err := fs.Stat(l.fsys, l.path(name)); err != nil || st.IsDir() {
+		return "", false
+	}
+	return name, true
+}
+
+type jetRenderer struct {
+	set *jet.Set
+}
+
+// NewTTRendererFS returns a Renderer for the jet templates in fsys. tt only
+// reads directories, so the set is built here with the same filters tt
+// registers (noescape, pathescape, queryescape).
+func NewTTRendererFS(fsys fs.FS) Renderer {
+
+	set := jet.NewHTMLSetLoader(&fsLoader{fsys: fsys})
+
+	set.AddGlobal("noescape", jet.SafeWriter(func(w io.Writer, b []byte) {
+		w.Write(b)
+	}))
+
+	set.AddGlobal("pathescape", jet.SafeWriter(func(w io.Writer, b []byte) {
+		w.Write([]byte(url.PathEscape(string(b))))
+	}))
+
+	set.AddGlobal("queryescape", jet.SafeWriter(func(w io.Writer, b []byte) {
+		w.Write([]byte(url.QueryEscape(string(b))))
+	}))
+
+	return &jetRenderer{set: set}
+}
+
+func (r *jetRenderer) Render(name string, vars map[string]interface{}) ([]byte, error) {
+
+	t, err := r.set.GetTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	if err := t.Execute(&buf, ttVars(vars), nil); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type htmlRenderer struct {
+	t *template.Template
+}
+
+// NewHTMLRenderer parses every file with extension ext under dir as
+// html/template. Templates are named by their slash separated path relative
+// to dir, e.g. "users/list.html", so they can include each other by name.
+func NewHTMLRenderer(dir string, ext string, funcs template.FuncMap) (Renderer, error) {
+	return NewHTMLRendererFS(os.DirFS(dir), ext, funcs)
+}
+
+// NewHTMLRendererFS is NewHTMLRenderer for templates in fsys.
+func NewHTMLRendererFS(fsys fs.FS, ext string, funcs template.FuncMap) (Renderer, error) {
+
+	ext = normExt(ext)
+	root := template.New("").Funcs(funcs)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !strings.HasSuffix(name, ext) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = root.New(name).Parse(string(data))
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &htmlRenderer{t: root}, nil
+}
+
+func (r *htmlRenderer) Render(name string, vars map[string]interface{}) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	if err := r.t.ExecuteTemplate(&buf, name, vars); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
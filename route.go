@@ -0,0 +1,118 @@
+package serv
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Route describes a registered endpoint for generated API documents. The
+// setters only add documentation, they do not change how requests are served.
+type Route struct {
+	method      string
+	path        string
+	summary     string
+	description string
+	tags        []string
+	input       reflect.Type
+	output      reflect.Type
+	auth        bool
+	hidden      bool
+}
+
+func (r *Route) Method() string {
+	return r.method
+}
+
+func (r *Route) Path() string {
+	return r.path
+}
+
+func (r *Route) Summary(text string) *Route {
+	r.summary = text
+	return r
+}
+
+func (r *Route) Description(text string) *Route {
+	r.description = text
+	return r
+}
+
+func (r *Route) Tags(tags ...string) *Route {
+	r.tags = append(r.tags, tags...)
+	return r
+}
+
+// Input sets the type the handler binds, e.g. Input(CreateUser{}). Fields
+// with path, query, header and cookie tags become parameters, form fields a
+// form body and the rest a JSON body.
+func (r *Route) Input(v interface{}) *Route {
+	r.input = typeOf(v)
+	return r
+}
+
+// Output sets the type written with status 200.
+func (r *Route) Output(v interface{}) *Route {
+	r.output = typeOf(v)
+	return r
+}
+
+// Hide leaves the route out of generated documents.
+func (r *Route) Hide() *Route {
+	r.hidden = true
+	return r
+}
+
+func typeOf(v interface{}) reflect.Type {
+	if t, ok := v.(reflect.Type); ok {
+		return t
+	}
+	return reflect.TypeOf(v)
+}
+
+// apiPath converts router patterns to OpenAPI ones: /users/:id becomes
+// /users/{id}, a trailing * becomes {path}.
+func apiPath(path string) (string, []string) {
+
+	list := path2list(path)
+	if len(list) < 2 {
+		return "/", nil
+	}
+
+	var names []string
+
+	for i, item := range list[1:] {
+		switch {
+		case item == "*":
+			item = "{path}"
+			names = append(names, "path")
+		case strings.HasPrefix(item, ":"):
+			names = append(names, item[1:])
+			item = "{" + item[1:] + "}"
+		}
+		list[i+1] = item
+	}
+
+	return "/" + strings.Join(list[1:], "/"), names
+}
+
+// addRoute returns the metadata of method and path, a route registered
+// again starts from scratch but keeps its place in the documents.
+func (s *Server) addRoute(method string, path string) *Route {
+
+	key := method + " " + path
+
+	if r, has := s.index[key]; has {
+		*r = Route{method: method, path: path}
+		return r
+	}
+
+	if s.index == nil {
+		s.index = make(map[string]*Route)
+	}
+
+	r := &Route{method: method, path: path}
+	s.routes = append(s.routes, r)
+	s.index[key] = r
+
+	return r
+}
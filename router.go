@@ -3,25 +3,46 @@ package serv
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/wmentor/latency"
-	"github.com/wmentor/tt"
 	"github.com/wmentor/uniq"
 )
 
 type Handler func(c *Context)
+type Middleware func(Handler) Handler
 type ErrorHandler func(error)
 type AuthCheck func(user string, passwd string) bool
 
 type fileHandler struct {
-	Filename string
+	fs   http.FileSystem
+	name string
+	cm   *compression
 }
 
 func (fh *fileHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	http.ServeFile(rw, req, fh.Filename)
+
+	if fh.cm.servePrecompressed(rw, req, fh.fs, fh.name) {
+		return
+	}
+
+	f, err := fh.fs.Open(fh.name)
+	if err != nil {
+		http.NotFound(rw, req)
+		return
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		http.NotFound(rw, req)
+		return
+	}
+
+	http.ServeContent(rw, req, st.Name(), st.ModTime(), f)
 }
 
 type node struct {
@@ -43,42 +64,63 @@ type router struct {
 	longQueryDuration time.Duration
 	longQueryHandler  LongQueryHandler
 	errorHandler      ErrorHandler
-	staticHandlers    map[string]http.Handler
+	staticHandlers    []*StaticHandler
 	fileHandlers      map[string]http.Handler
 	authCheck         AuthCheck
-	tt                *tt.TT
+	templates         *templates
+	middlewares       []Middleware
+	trustedProxies    []*net.IPNet
+	denyList          *IPList
+	allowList         *IPList
+	blockHandler      BlockHandler
+	maxBodySize       int64
+	formMemory        int64
+	codecs            *codecs
+	compression       *compression
+	wsOptions         *WSOptions
+	secure            *SecureOptions
+}
+
+func (r *router) chain(fn Handler) Handler {
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		fn = r.middlewares[i](fn)
+	}
+	return fn
 }
 
 func (sr *router) optionsOrNotFound(c *Context) {
 	if sr.optionsFunc != nil && c.Method() == "OPTIONS" {
-		sr.optionsFunc(c)
+		sr.chain(sr.optionsFunc)(c)
 	} else {
-		sr.notFoundFunc(c)
+		sr.chain(sr.notFoundFunc)(c)
 	}
 }
 
 func (r *router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
-	if handler, has := r.fileHandlers[req.URL.Path]; has {
-		handler.ServeHTTP(rw, req)
-		return
-	}
-
-	for pref, handler := range r.staticHandlers {
-		if strings.HasPrefix(req.URL.Path, pref) {
-			handler.ServeHTTP(rw, req)
-			return
-		}
+	if cw := r.compression.wrap(rw, req); cw != nil {
+		defer cw.Close()
+		rw = cw
 	}
 
 	workTime := latency.New()
 
+	body := &countingBody{ReadCloser: req.Body}
+	if req.Body != nil {
+		req.Body = body
+	}
+
 	ctx := &Context{
-		rw:           rw,
-		req:          req,
-		params:       make(map[string]string),
-		errorHandler: r.errorHandler,
-		tt:           r.tt,
+		rw:             newResponseWriter(rw),
+		req:            req,
+		params:         make(map[string]string),
+		errorHandler:   r.errorHandler,
+		templates:      r.templates,
+		trustedProxies: r.trustedProxies,
+		blockHandler:   r.blockHandler,
+		body:           req.Body,
+		formMemory:     r.formMemory,
+		codecs:         r.codecs,
 	}
 
 	defer func() {
@@ -90,12 +132,18 @@ func (r *router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 				Addr:       ctx.RemoteAddr(),
 				Auth:       "-",
 				RequestURL: ctx.req.RequestURI,
-				StatusCode: ctx.statusCode,
+				StatusCode: http.StatusOK,
+				BytesIn:    body.size,
+				BytesOut:   ctx.rw.size,
 				Referer:    ctx.GetHeader("Referer"),
 				UserAgent:  ctx.GetHeader("User-Agent"),
 				UID:        ctx.Cookie("uid"),
 			}
 
+			if ctx.rw.written {
+				ld.StatusCode = ctx.rw.status
+			}
+
 			if user, _, ok := ctx.BasicAuth(); ok {
 				ld.Auth = user
 			}
@@ -111,6 +159,32 @@ func (r *router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	}()
 
+	if r.denyList != nil || r.allowList != nil {
+		ip := ctx.RemoteAddr()
+		if (r.denyList != nil && r.denyList.ContainsString(ip)) || (r.allowList != nil && !r.allowList.ContainsString(ip)) {
+			ctx.blockIP(ip)
+			return
+		}
+	}
+
+	if handler, has := r.fileHandlers[req.URL.Path]; has {
+		if r.secure != nil {
+			r.secure.apply(ctx)
+		}
+		handler.ServeHTTP(ctx.rw, req)
+		return
+	}
+
+	for _, handler := range r.staticHandlers {
+		if strings.HasPrefix(req.URL.Path, handler.prefix) {
+			if r.secure != nil {
+				r.secure.apply(ctx)
+			}
+			handler.ServeHTTP(ctx.rw, req)
+			return
+		}
+	}
+
 	if r.needUid {
 		makeUid(rw, req)
 	}
@@ -124,7 +198,17 @@ func (r *router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	defer func() {
 		if re := recover(); re != nil {
-			r.internalErrorFunc(ctx)
+			if he, ok := re.(HTTPError); ok {
+				if !ctx.rw.written {
+					ctx.WriteError(he)
+				} else if he.StatusCode() >= 500 && r.errorHandler != nil {
+					r.errorHandler(he)
+				}
+				return
+			}
+			if !ctx.rw.written {
+				r.internalErrorFunc(ctx)
+			}
 			if r.errorHandler != nil {
 				r.errorHandler(errors.New(fmt.Sprint(re)))
 			}
@@ -132,6 +216,8 @@ func (r *router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	}()
 
+	ctx.limitBody(r.maxBodySize)
+
 	root, has := r.methods[req.Method]
 	if !has {
 		r.optionsOrNotFound(ctx)
@@ -179,7 +265,10 @@ func (r *router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	if root.fn != nil {
 		ctx.params = params
-		root.fn(ctx)
+		r.chain(root.fn)(ctx)
+		if ctx.bodyTooLarge && !ctx.rw.written {
+			ctx.StandardError(413)
+		}
 	} else {
 		r.optionsOrNotFound(ctx)
 	}
@@ -135,3 +135,97 @@ SOFTWARE.
 
 	tJRPC("hello", "wmentor", `"result":"Hello, wmentor!"`)
 }
+
+func TestBodyLimit(t *testing.T) {
+
+	s := New()
+	s.SetMaxBodySize(16)
+
+	var bodyErr error
+
+	s.Register("POST", "/json", func(c *Context) {
+		var v interface{}
+		if bodyErr = c.BodyJson(&v); bodyErr != nil {
+			return
+		}
+		c.WriteHeader(200)
+	})
+
+	s.Register("POST", "/big", MaxBodySize(1024)(func(c *Context) {
+		var v interface{}
+		if bodyErr = c.BodyJson(&v); bodyErr != nil {
+			c.StandardError(400)
+			return
+		}
+		c.WriteHeader(200)
+	}))
+
+	tP := func(url string, body string, chunked bool, code int) {
+
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code {
+			t.Fatalf("%s returns %d wait %d", url, rw.Code, code)
+		}
+	}
+
+	long := `"` + strings.Repeat("x", 100) + `"`
+
+	tP("/json", `{"a":1}`, false, 200)
+	tP("/json", long, false, 413)
+	tP("/json", long, true, 413)
+
+	if bodyErr != ErrBodyTooLarge {
+		t.Fatal("ErrBodyTooLarge expected")
+	}
+
+	tP("/big", long, false, 200)
+	tP("/big", long, true, 200)
+}
+
+func TestResponseRecorder(t *testing.T) {
+
+	s := New()
+
+	var ld *LogData
+
+	s.SetLogger(func(d *LogData) { ld = d })
+
+	s.Register("POST", "/write", func(c *Context) {
+		data, _ := ioutil.ReadAll(c.Body())
+		c.Write(data)
+	})
+
+	s.Register("GET", "/panic", func(c *Context) {
+		c.WriteHeader(202)
+		c.WriteString("partial")
+		panic("boom")
+	})
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("POST", "/write", strings.NewReader("hello")))
+
+	if ld == nil || ld.StatusCode != 200 || ld.BytesIn != 5 || ld.BytesOut != 5 || rw.Body.String() != "hello" {
+		t.Fatalf("invalid log data: %+v", ld)
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/panic", nil))
+
+	if rw.Code != 202 || rw.Body.String() != "partial" || ld.StatusCode != 202 || ld.BytesOut != 7 {
+		t.Fatalf("invalid panic reply: %d %q %+v", rw.Code, rw.Body.String(), ld)
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/unknown", nil))
+
+	if ld.StatusCode != 404 {
+		t.Fatalf("invalid log data: %+v", ld)
+	}
+}
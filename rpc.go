@@ -0,0 +1,370 @@
+package serv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+
+	"github.com/wmentor/jrpc"
+)
+
+const (
+	RPCParseError     int64 = -32700
+	RPCInvalidRequest int64 = -32600
+	RPCMethodNotFound int64 = -32601
+	RPCInvalidParams  int64 = -32602
+	RPCInternalError  int64 = -32603
+	RPCServerError    int64 = -32000
+)
+
+// RPCFunc is a registered method as seen by RPC middleware.
+type RPCFunc func(c *Context, method string, params json.RawMessage) (interface{}, error)
+
+type RPCMiddleware func(RPCFunc) RPCFunc
+
+var (
+	contextType = reflect.TypeOf((*Context)(nil))
+	jrpcErrType = reflect.TypeOf((*jrpc.Error)(nil))
+)
+
+type rpcRequest struct {
+	JsonRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Id      json.RawMessage `json:"id"`
+}
+
+// RPC is a JSON-RPC endpoint with its own methods. Only the endpoint
+// created by Server.RegisterJsonRPC also serves the methods registered on
+// the Server, its own methods take precedence.
+type RPC struct {
+	server    *Server
+	url       string
+	funcs     map[string]RPCFunc
+	methods   map[string]RPCFunc
+	docs      map[string]*RPCMethod
+	mws       []RPCMiddleware
+	fallback  *RPC
+	inheritor []*RPC
+}
+
+// newRPC creates an endpoint; a non-nil fallback shares its methods with it.
+func newRPC(s *Server, url string, fallback *RPC) *RPC {
+
+	rpc := &RPC{
+		server:   s,
+		url:      url,
+		funcs:    make(map[string]RPCFunc),
+		methods:  make(map[string]RPCFunc),
+		docs:     make(map[string]*RPCMethod),
+		fallback: fallback,
+	}
+
+	if fallback != nil {
+		fallback.inheritor = append(fallback.inheritor, rpc)
+		rpc.rebuild()
+	}
+
+	return rpc
+}
+
+// RPCMethod describes a registered method for the generated OpenRPC document.
+type RPCMethod struct {
+	name        string
+	fn          reflect.Type
+	summary     string
+	description string
+	tags        []string
+}
+
+func (m *RPCMethod) Summary(text string) *RPCMethod {
+	m.summary = text
+	return m
+}
+
+func (m *RPCMethod) Description(text string) *RPCMethod {
+	m.description = text
+	return m
+}
+
+func (m *RPCMethod) Tags(tags ...string) *RPCMethod {
+	m.tags = append(m.tags, tags...)
+	return m
+}
+
+// RegMethod registers fn as method. fn may take *Context as the first
+// argument and at most one params argument, and returns a result, a result
+// and an error, or nothing. Errors of type *jrpc.Error are sent as is, other
+// errors are passed to the error handler and the client gets a generic
+// "Server error". mws wrap this method only.
+func (rpc *RPC) RegMethod(method string, fn interface{}, mws ...RPCMiddleware) *RPCMethod {
+
+	f := makeRPCFunc(method, fn)
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		f = mws[i](f)
+	}
+
+	rpc.funcs[method] = f
+	rpc.setMethod(method, f)
+
+	doc := &RPCMethod{name: method, fn: reflect.TypeOf(fn)}
+	rpc.docs[method] = doc
+
+	return doc
+}
+
+// Use adds middleware that wraps every method called through this endpoint.
+// It should be called before the endpoint starts serving.
+func (rpc *RPC) Use(mws ...RPCMiddleware) {
+	rpc.mws = append(rpc.mws, mws...)
+	rpc.rebuild()
+}
+
+func (rpc *RPC) wrap(fn RPCFunc) RPCFunc {
+	for i := len(rpc.mws) - 1; i >= 0; i-- {
+		fn = rpc.mws[i](fn)
+	}
+	return fn
+}
+
+// setMethod wraps fn with the endpoint middleware and passes it on to the
+// endpoints that inherit methods and have no own method with this name.
+func (rpc *RPC) setMethod(method string, fn RPCFunc) {
+
+	wrapped := rpc.wrap(fn)
+	rpc.methods[method] = wrapped
+
+	for _, in := range rpc.inheritor {
+		if _, own := in.funcs[method]; !own {
+			in.setMethod(method, wrapped)
+		}
+	}
+}
+
+// rebuild wraps all methods again after the middleware has changed.
+func (rpc *RPC) rebuild() {
+
+	rpc.methods = make(map[string]RPCFunc, len(rpc.funcs))
+
+	if rpc.fallback != nil {
+		for k, fn := range rpc.fallback.methods {
+			rpc.methods[k] = rpc.wrap(fn)
+		}
+	}
+
+	for k, fn := range rpc.funcs {
+		rpc.methods[k] = rpc.wrap(fn)
+	}
+
+	for _, in := range rpc.inheritor {
+		in.rebuild()
+	}
+}
+
+func (rpc *RPC) lookup(method string) (RPCFunc, bool) {
+	fn, has := rpc.methods[method]
+	return fn, has
+}
+
+// Handle serves JSON-RPC requests, so an endpoint can be registered as a
+// Handler anywhere, e.g. in a route group.
+func (rpc *RPC) Handle(c *Context) {
+	rpc.serve(c)
+}
+
+func makeRPCFunc(method string, fn interface{}) RPCFunc {
+
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+
+	if ft.Kind() != reflect.Func {
+		panic("serv: rpc method " + method + " is not a function")
+	}
+
+	withCtx := ft.NumIn() > 0 && ft.In(0) == contextType
+
+	first := 0
+	if withCtx {
+		first = 1
+	}
+
+	if ft.NumIn()-first > 1 || ft.NumOut() > 2 || ft.NumOut() == 2 && !ft.Out(1).Implements(errorType) {
+		panic("serv: invalid signature of rpc method " + method)
+	}
+
+	var in reflect.Type
+	if ft.NumIn() > first {
+		in = ft.In(first)
+	}
+
+	return func(c *Context, method string, params json.RawMessage) (interface{}, error) {
+
+		args := make([]reflect.Value, 0, 2)
+
+		if withCtx {
+			args = append(args, reflect.ValueOf(c))
+		}
+
+		if in != nil {
+			arg := reflect.New(in)
+			if len(params) > 0 && string(params) != "null" {
+				if err := json.Unmarshal(params, arg.Interface()); err != nil {
+					return nil, &jrpc.Error{Code: RPCInvalidParams, Message: "Invalid params"}
+				}
+			}
+			args = append(args, arg.Elem())
+		}
+
+		outs := fv.Call(args)
+
+		if len(outs) > 0 {
+			last := outs[len(outs)-1]
+			if last.Type().Implements(errorType) {
+				outs = outs[:len(outs)-1]
+				if last.Kind() != reflect.Ptr && last.Kind() != reflect.Interface || !last.IsNil() {
+					return nil, last.Interface().(error)
+				}
+			}
+		}
+
+		if len(outs) == 0 {
+			return nil, nil
+		}
+
+		return outs[0].Interface(), nil
+	}
+}
+
+func rpcError(id json.RawMessage, code int64, message string) *jrpc.ErrResponse {
+	res := &jrpc.ErrResponse{JsonRPC: "2.0", Error: &jrpc.Error{Code: code, Message: message}}
+	if len(id) > 0 {
+		res.Id = &id
+	}
+	return res
+}
+
+// rpcStatus maps JSON-RPC error codes to HTTP statuses of single calls.
+// Application errors are regular responses and keep 200.
+func rpcStatus(code int64) int {
+	switch code {
+	case RPCParseError, RPCInvalidRequest, RPCInvalidParams:
+		return http.StatusBadRequest
+	case RPCMethodNotFound:
+		return http.StatusNotFound
+	case RPCInternalError:
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// call runs a single request. It returns nil for notifications.
+func (rpc *RPC) call(c *Context, data json.RawMessage) (resp interface{}, status int) {
+
+	var req rpcRequest
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		return rpcError(nil, RPCInvalidRequest, "Invalid Request"), http.StatusBadRequest
+	}
+
+	if req.JsonRPC != "2.0" || req.Method == "" {
+		return rpcError(req.Id, RPCInvalidRequest, "Invalid Request"), http.StatusBadRequest
+	}
+
+	notification := len(req.Id) == 0
+
+	fn, has := rpc.lookup(req.Method)
+	if !has {
+		if notification {
+			return nil, http.StatusNoContent
+		}
+		return rpcError(req.Id, RPCMethodNotFound, "Method not found"), http.StatusNotFound
+	}
+
+	defer func() {
+		if re := recover(); re != nil {
+			if eh := rpc.server.router.errorHandler; eh != nil {
+				eh(fmt.Errorf("rpc %s: %v", req.Method, re))
+			}
+			if notification {
+				resp, status = nil, http.StatusNoContent
+			} else {
+				resp, status = rpcError(req.Id, RPCInternalError, "Internal error"), http.StatusInternalServerError
+			}
+		}
+	}()
+
+	res, err := fn(c, req.Method, req.Params)
+
+	if notification {
+		return nil, http.StatusNoContent
+	}
+
+	if err != nil {
+		var je *jrpc.Error
+		if !errors.As(err, &je) {
+			if eh := rpc.server.router.errorHandler; eh != nil {
+				eh(fmt.Errorf("rpc %s: %w", req.Method, err))
+			}
+			je = &jrpc.Error{Code: RPCServerError, Message: "Server error"}
+		}
+		return rpcError(req.Id, je.Code, je.Message), rpcStatus(je.Code)
+	}
+
+	return &jrpc.Response{Id: &req.Id, JsonRPC: "2.0", Result: res}, http.StatusOK
+}
+
+// serve handles a JSON-RPC 2.0 call or batch. Calls that are all
+// notifications get 204 without a body.
+func (rpc *RPC) serve(c *Context) {
+
+	var resp interface{}
+	status := http.StatusOK
+
+	data, err := ioutil.ReadAll(c.Body())
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case err != nil && c.bodyTooLarge:
+		resp, status = rpcError(nil, RPCInvalidRequest, "Request too large"), http.StatusRequestEntityTooLarge
+
+	case err != nil || !json.Valid(data):
+		resp, status = rpcError(nil, RPCParseError, "Parse error"), http.StatusBadRequest
+
+	case data[0] == '[':
+		var items []json.RawMessage
+		json.Unmarshal(data, &items)
+
+		if len(items) == 0 {
+			resp, status = rpcError(nil, RPCInvalidRequest, "Invalid Request"), http.StatusBadRequest
+			break
+		}
+
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if r, _ := rpc.call(c, item); r != nil {
+				list = append(list, r)
+			}
+		}
+
+		if len(list) > 0 {
+			resp = list
+		}
+
+	default:
+		resp, status = rpc.call(c, data)
+	}
+
+	if resp == nil {
+		c.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	c.SetContentType("application/json; charset=utf-8")
+	c.WriteHeader(status)
+	encodeJSON(c.rw, resp)
+}
@@ -0,0 +1,162 @@
+package serv
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wmentor/jrpc"
+)
+
+func TestJsonRPC(t *testing.T) {
+
+	s := New()
+
+	var notified []string
+
+	requireUser := func(next RPCFunc) RPCFunc {
+		return func(c *Context, method string, params json.RawMessage) (interface{}, error) {
+			if c.GetHeader("X-User") == "" {
+				return nil, &jrpc.Error{Code: -32001, Message: "Unauthorized"}
+			}
+			return next(c, method, params)
+		}
+	}
+
+	s.RegMethod("sum", func(list []int) (int, *jrpc.Error) {
+		res := 0
+		for _, v := range list {
+			res += v
+		}
+		return res, nil
+	})
+
+	s.RegMethod("whoami", func(c *Context) (string, error) {
+		return c.GetHeader("X-User"), nil
+	}, requireUser)
+
+	s.RegMethod("notify", func(msg string) {
+		notified = append(notified, msg)
+	})
+
+	s.RegMethod("fail", func() (interface{}, error) {
+		return nil, errors.New("storage is down")
+	})
+
+	s.RegMethod("crash", func() int {
+		panic("boom")
+	})
+
+	s.RegisterJsonRPC("/rpc")
+
+	var rpcErr error
+
+	s.SetErrorHandler(func(err error) {
+		rpcErr = err
+	})
+
+	tJRPC := func(body string, user string, code int, res string) {
+
+		req := httptest.NewRequest("POST", "/rpc", strings.NewReader(body))
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code || strings.TrimSpace(rw.Body.String()) != res {
+			t.Fatalf("%s: %d %s", body, rw.Code, rw.Body.String())
+		}
+	}
+
+	tJRPC(`{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2,3]}`, "", 200, `{"id":1,"jsonrpc":"2.0","result":6}`)
+	tJRPC(`{"jsonrpc":"2.0","id":"a","method":"whoami"}`, "bob", 200, `{"id":"a","jsonrpc":"2.0","result":"bob"}`)
+	tJRPC(`{"jsonrpc":"2.0","id":2,"method":"whoami"}`, "", 200, `{"id":2,"jsonrpc":"2.0","error":{"code":-32001,"message":"Unauthorized"}}`)
+	tJRPC(`{"jsonrpc":"2.0","id":3,"method":"none"}`, "", 404, `{"id":3,"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"}}`)
+	tJRPC(`{"jsonrpc":"2.0","id":4,"method":"sum","params":"x"}`, "", 400, `{"id":4,"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"}}`)
+	tJRPC(`{"jsonrpc":"2.0","id":5,"method":"fail"}`, "", 200, `{"id":5,"jsonrpc":"2.0","error":{"code":-32000,"message":"Server error"}}`)
+
+	if rpcErr == nil || !strings.Contains(rpcErr.Error(), "storage is down") {
+		t.Fatalf("method error not reported: %v", rpcErr)
+	}
+
+	tJRPC(`{"jsonrpc":"2.0","id":6,"method":"crash"}`, "", 500, `{"id":6,"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"}}`)
+	tJRPC(`{"jsonrpc":"2.0","method":"notify","params":"hi"}`, "", 204, ``)
+	tJRPC(`{"jsonrpc":"2.0","method":1}`, "", 400, `{"id":null,"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"}}`)
+	tJRPC(`{"jsonrpc":"2.0","method":"sum"`, "", 400, `{"id":null,"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"}}`)
+	tJRPC(`[]`, "", 400, `{"id":null,"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"}}`)
+	tJRPC(`[{"jsonrpc":"2.0","method":"notify","params":"a"},{"jsonrpc":"2.0","method":"notify","params":"b"}]`, "", 204, ``)
+	tJRPC(`[{"jsonrpc":"2.0","id":1,"method":"sum","params":[1]},{"jsonrpc":"2.0","method":"notify","params":"c"},1]`, "", 200,
+		`[{"id":1,"jsonrpc":"2.0","result":1},{"id":null,"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"}}]`)
+
+	if strings.Join(notified, ",") != "hi,a,b,c" {
+		t.Fatalf("invalid notifications: %v", notified)
+	}
+}
+
+func TestJsonRPCNamespaces(t *testing.T) {
+
+	s := New()
+
+	s.SetAuthCheck(func(user, passwd string) bool {
+		return user == "admin" && passwd == "secret"
+	})
+
+	s.RegMethod("ping", func() string { return "pong" })
+
+	public := s.RegisterJsonRPC("/rpc/public")
+	public.RegMethod("hello", func(name string) string { return "hello " + name })
+
+	admin := s.RegisterJsonRPCAuth("/rpc/admin")
+	admin.RegMethod("drop", func(c *Context) (string, error) {
+		user, _, _ := c.BasicAuth()
+		return "dropped by " + user, nil
+	})
+
+	api := s.Group("/api").RegisterJsonRPC("/rpc")
+	api.RegMethod("hello", func(name string) string { return "api hello " + name })
+
+	calls := 0
+
+	public.Use(func(next RPCFunc) RPCFunc {
+		return func(c *Context, method string, params json.RawMessage) (interface{}, error) {
+			calls++
+			return next(c, method, params)
+		}
+	})
+
+	s.RegMethod("version", func() string { return "1.0" })
+
+	tN := func(url string, method string, auth bool, code int, res string) {
+
+		req := httptest.NewRequest("POST", url, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":"bob"}`))
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code || !strings.Contains(rw.Body.String(), res) {
+			t.Fatalf("%s %s: %d %s", url, method, rw.Code, rw.Body.String())
+		}
+	}
+
+	tN("/rpc/public", "hello", false, 200, `"result":"hello bob"`)
+	tN("/rpc/public", "ping", false, 200, `"result":"pong"`)
+	tN("/rpc/public", "version", false, 200, `"result":"1.0"`)
+	tN("/rpc/public", "drop", false, 404, `"code":-32601`)
+	tN("/rpc/admin", "drop", false, 401, ``)
+	tN("/rpc/admin", "drop", true, 200, `"result":"dropped by admin"`)
+	tN("/rpc/admin", "hello", true, 404, `"code":-32601`)
+	tN("/rpc/admin", "ping", true, 404, `"code":-32601`)
+	tN("/api/rpc", "hello", false, 200, `"result":"api hello bob"`)
+	tN("/api/rpc", "ping", false, 404, `"code":-32601`)
+
+	if calls != 3 {
+		t.Fatalf("endpoint middleware called %d times", calls)
+	}
+}
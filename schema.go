@@ -0,0 +1,258 @@
+package serv
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	uuidType          = reflect.TypeOf(UUID{})
+
+	schemaNameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// schemaBuilder turns Go types into JSON schemas. Named structs are put in
+// components once and referenced from everywhere else.
+type schemaBuilder struct {
+	refPrefix  string
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder(refPrefix string) *schemaBuilder {
+	return &schemaBuilder{
+		refPrefix:  refPrefix,
+		components: make(map[string]interface{}),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case uuidType:
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t, nil)
+		}
+		return map[string]interface{}{"$ref": b.refPrefix + b.component(t)}
+	}
+
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) component(t reflect.Type) string {
+
+	if name, has := b.names[t]; has {
+		return name
+	}
+
+	name := schemaNameRe.ReplaceAllString(t.Name(), "_")
+	for i := 2; b.components[name] != nil; i++ {
+		name = schemaNameRe.ReplaceAllString(t.Name(), "_") + strconv.Itoa(i)
+	}
+
+	b.names[t] = name
+	b.components[name] = map[string]interface{}{}
+	b.components[name] = b.object(t, nil)
+
+	return name
+}
+
+// object builds an object schema of the JSON fields of t; skip leaves out
+// fields that are bound from other sources.
+func (b *schemaBuilder) object(t reflect.Type, skip func(sf reflect.StructField) bool) map[string]interface{} {
+
+	props := make(map[string]interface{})
+	var required []string
+
+	b.fields(t, skip, props, &required)
+
+	res := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		res["required"] = required
+	}
+
+	return res
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, skip func(sf reflect.StructField) bool, props map[string]interface{}, required *[]string) {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+
+		if skip != nil && skip(sf) {
+			continue
+		}
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.fields(ft, skip, props, required)
+				continue
+			}
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		ps := b.schema(sf.Type)
+		if applyRules(ps, sf.Type, parseRules(sf.Tag.Get("validate"))) {
+			*required = append(*required, name)
+		}
+
+		props[name] = ps
+	}
+}
+
+// applyRules adds validate constraints to a schema and reports whether the field is required.
+func applyRules(s map[string]interface{}, t reflect.Type, rules []rule) bool {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	_, isRef := s["$ref"]
+	required := false
+
+	for _, r := range rules {
+
+		if r.name == "required" {
+			required = true
+			continue
+		}
+
+		if isRef {
+			continue
+		}
+
+		num, _ := strconv.ParseFloat(r.param, 64)
+
+		switch r.name {
+		case "min", "max", "len":
+			var key string
+
+			switch t.Kind() {
+			case reflect.String:
+				key = "Length"
+			case reflect.Slice, reflect.Array:
+				key = "Items"
+			case reflect.Map:
+				key = "Properties"
+			default:
+				if r.name == "min" {
+					s["minimum"] = num
+				} else if r.name == "max" {
+					s["maximum"] = num
+				}
+				continue
+			}
+
+			if r.name != "max" {
+				s["min"+key] = num
+			}
+			if r.name != "min" {
+				s["max"+key] = num
+			}
+
+		case "regex":
+			s["pattern"] = r.param
+
+		case "email":
+			s["format"] = "email"
+
+		case "oneof":
+			var enum []interface{}
+			for _, item := range strings.Fields(r.param) {
+				if v, err := strconv.ParseFloat(item, 64); err == nil && s["type"] != "string" {
+					enum = append(enum, v)
+				} else {
+					enum = append(enum, item)
+				}
+			}
+			s["enum"] = enum
+		}
+	}
+
+	return required
+}
+
+// paramSchema is schema for values parsed from strings, where durations
+// are written as "1m30s".
+func (b *schemaBuilder) paramSchema(t reflect.Type) map[string]interface{} {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		return map[string]interface{}{"type": "string", "format": "duration"}
+	}
+
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		return map[string]interface{}{"type": "array", "items": b.paramSchema(t.Elem())}
+	}
+
+	return b.schema(t)
+}
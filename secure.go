@@ -0,0 +1,209 @@
+package serv
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type SecureOptions struct {
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	ContentTypeNosniff    bool
+	FrameOptions          string
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+	CSP                   *CSP
+}
+
+type CSP struct {
+	order      []string
+	directives map[string][]string
+	nonce      map[string]bool
+}
+
+func NewCSP() *CSP {
+	return &CSP{
+		directives: make(map[string][]string),
+		nonce:      make(map[string]bool),
+	}
+}
+
+// DefaultCSP allows resources from the same origin only and requires a nonce for inline scripts and styles.
+func DefaultCSP() *CSP {
+	return NewCSP().
+		Set("default-src", "'self'").
+		Set("script-src", "'self'").
+		Set("style-src", "'self'").
+		Set("img-src", "'self'", "data:").
+		Set("object-src", "'none'").
+		Set("base-uri", "'self'").
+		Set("form-action", "'self'").
+		Set("frame-ancestors", "'none'").
+		Nonce("script-src", "style-src")
+}
+
+func DefaultSecureOptions() *SecureOptions {
+	return &SecureOptions{
+		HSTSMaxAge:            63072000,
+		HSTSIncludeSubdomains: true,
+		ContentTypeNosniff:    true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		PermissionsPolicy:     "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		CSP:                   DefaultCSP(),
+	}
+}
+
+func (p *CSP) Set(directive string, sources ...string) *CSP {
+	if _, has := p.directives[directive]; !has {
+		p.order = append(p.order, directive)
+	}
+	p.directives[directive] = append([]string{}, sources...)
+	return p
+}
+
+func (p *CSP) Add(directive string, sources ...string) *CSP {
+	if _, has := p.directives[directive]; !has {
+		p.order = append(p.order, directive)
+	}
+	p.directives[directive] = append(p.directives[directive], sources...)
+	return p
+}
+
+func (p *CSP) Remove(directive string) *CSP {
+	if _, has := p.directives[directive]; !has {
+		return p
+	}
+	delete(p.directives, directive)
+	delete(p.nonce, directive)
+	for i, d := range p.order {
+		if d == directive {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	return p
+}
+
+func (p *CSP) Nonce(directives ...string) *CSP {
+	for _, d := range directives {
+		if _, has := p.directives[d]; !has {
+			p.order = append(p.order, d)
+			p.directives[d] = nil
+		}
+		p.nonce[d] = true
+	}
+	return p
+}
+
+func (p *CSP) NeedNonce() bool {
+	return len(p.nonce) > 0
+}
+
+func (p *CSP) Clone() *CSP {
+	res := NewCSP()
+	for _, d := range p.order {
+		res.Set(d, p.directives[d]...)
+		if p.nonce[d] {
+			res.nonce[d] = true
+		}
+	}
+	return res
+}
+
+func (p *CSP) Build(nonce string) string {
+	parts := make([]string, 0, len(p.order))
+
+	for _, d := range p.order {
+		list := p.directives[d]
+		if p.nonce[d] && nonce != "" {
+			list = append(list[:len(list):len(list)], "'nonce-"+nonce+"'")
+		}
+
+		if len(list) == 0 {
+			parts = append(parts, d)
+		} else {
+			parts = append(parts, d+" "+strings.Join(list, " "))
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func (o *SecureOptions) hsts() string {
+	v := "max-age=" + strconv.Itoa(o.HSTSMaxAge)
+	if o.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if o.HSTSPreload {
+		v += "; preload"
+	}
+	return v
+}
+
+// apply sets the headers described by o on the response of c.
+func (o *SecureOptions) apply(c *Context) {
+
+	h := c.rw.Header()
+
+	if o.HSTSMaxAge > 0 {
+		h.Set("Strict-Transport-Security", o.hsts())
+	} else {
+		h.Del("Strict-Transport-Security")
+	}
+
+	if o.ContentTypeNosniff {
+		h.Set("X-Content-Type-Options", "nosniff")
+	} else {
+		h.Del("X-Content-Type-Options")
+	}
+
+	setOrDel(h, "X-Frame-Options", o.FrameOptions)
+	setOrDel(h, "Referrer-Policy", o.ReferrerPolicy)
+	setOrDel(h, "Permissions-Policy", o.PermissionsPolicy)
+
+	if o.CSP != nil {
+		if o.CSP.NeedNonce() && c.nonce == "" {
+			c.nonce = makeNonce()
+		}
+		h.Set("Content-Security-Policy", o.CSP.Build(c.nonce))
+	} else {
+		h.Del("Content-Security-Policy")
+	}
+}
+
+// SecureHeaders sets the security headers described by opts (DefaultSecureOptions if nil).
+// Headers are replaced, not appended, so a route-level SecureHeaders overrides the global one.
+func SecureHeaders(opts *SecureOptions) Middleware {
+
+	if opts == nil {
+		opts = DefaultSecureOptions()
+	}
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			opts.apply(c)
+			next(c)
+		}
+	}
+}
+
+func setOrDel(h http.Header, key string, value string) {
+	if value != "" {
+		h.Set(key, value)
+	} else {
+		h.Del(key)
+	}
+}
+
+func makeNonce() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,108 @@
+package serv
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSecureHeaders(t *testing.T) {
+
+	s := New()
+	s.SetSecureHeaders(nil)
+
+	s.Register("GET", "/", func(c *Context) {
+		c.WriteHeader(200)
+		c.RenderStr(`{{ cspNonce }}`, nil)
+	})
+
+	embed := DefaultSecureOptions()
+	embed.FrameOptions = ""
+	embed.CSP = DefaultCSP().Set("frame-ancestors", "https://example.com")
+
+	s.Register("GET", "/embed", SecureHeaders(embed)(func(c *Context) {
+		c.WriteHeader(200)
+		c.WriteString(c.CSPNonce())
+	}))
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+
+	res := rw.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	nonce := string(body)
+
+	if nonce == "" {
+		t.Fatal("nonce not rendered")
+	}
+
+	if res.Header.Get("X-Frame-Options") != "DENY" || res.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatal("default headers not set")
+	}
+
+	if res.Header.Get("Strict-Transport-Security") != "max-age=63072000; includeSubDomains" {
+		t.Fatal("invalid HSTS header")
+	}
+
+	csp := res.Header.Get("Content-Security-Policy")
+	if !strings.Contains(csp, "script-src 'self' 'nonce-"+nonce+"'") || !strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Fatalf("invalid CSP: %s", csp)
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/embed", nil))
+
+	res = rw.Result()
+	body, _ = ioutil.ReadAll(res.Body)
+	embedNonce := string(body)
+
+	if _, has := res.Header["X-Frame-Options"]; has {
+		t.Fatal("X-Frame-Options not overridden")
+	}
+
+	v := res.Header["Content-Security-Policy"]
+	if len(v) != 1 || !strings.Contains(v[0], "frame-ancestors https://example.com") || strings.Contains(v[0], "frame-ancestors 'none'") {
+		t.Fatalf("CSP not overridden: %v", v)
+	}
+
+	if embedNonce == "" || !strings.Contains(v[0], "'nonce-"+embedNonce+"'") {
+		t.Fatalf("route CSP must use the request nonce: %v", v)
+	}
+}
+
+func TestSecureHeadersStatic(t *testing.T) {
+
+	s := New()
+	s.SetSecureHeaders(nil)
+
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<app>")},
+		"app.js":     {Data: []byte("app")},
+	}
+
+	s.StaticFS("/app", fsys).SetSPA(true)
+	s.FileFS("/favicon.ico", fsys, "app.js")
+
+	tS := func(url string, body string) {
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, httptest.NewRequest("GET", url, nil))
+
+		h := rw.Header()
+
+		if rw.Code != 200 || rw.Body.String() != body {
+			t.Fatalf("%s returns %d %q", url, rw.Code, rw.Body.String())
+		}
+
+		if h.Get("X-Frame-Options") != "DENY" || h.Get("X-Content-Type-Options") != "nosniff" ||
+			h.Get("Strict-Transport-Security") == "" || !strings.Contains(h.Get("Content-Security-Policy"), "default-src 'self'") {
+			t.Fatalf("%s without security headers: %v", url, h)
+		}
+	}
+
+	tS("/app/app.js", "app")
+	tS("/app/users/7", "<app>")
+	tS("/favicon.ico", "app")
+}
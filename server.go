@@ -1,20 +1,22 @@
 package serv
 
 import (
-	"bytes"
 	"context"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
-
-	"github.com/wmentor/jrpc"
-	"github.com/wmentor/tt"
 )
 
 type Server struct {
 	router *router
 	server *http.Server
-	jrpc   jrpc.JRPC
+	rpc    *RPC
+	routes []*Route
+	index  map[string]*Route
 }
 
 func New() *Server {
@@ -27,13 +29,16 @@ func New() *Server {
 		notFoundFunc:      func(c *Context) { c.StandardError(404) },
 		badRequestFunc:    func(c *Context) { c.StandardError(400) },
 		internalErrorFunc: func(c *Context) { c.StandardError(500) },
-		staticHandlers:    make(map[string]http.Handler),
 		fileHandlers:      make(map[string]http.Handler),
 		authCheck:         func(login string, passwd string) bool { return false },
-		tt:                tt.New(),
+		templates:         newTemplates(),
+		codecs:            newCodecs(),
+		compression:       newCompression(),
 	}
 
-	s.jrpc = jrpc.New()
+	s.rpc = newRPC(s, "", nil)
+
+	s.router.templates.addGlobal("asset", s.Asset)
 
 	return s
 }
@@ -84,22 +89,130 @@ func (s *Server) SetLogger(l Logger) {
 	s.router.logger = l
 }
 
-func (s *Server) Static(prefix string, dir string) {
+// SetTrustedProxies sets addresses and CIDR ranges of reverse proxies whose
+// Forwarded, X-Forwarded-For and X-Real-Ip headers are honoured by RemoteAddr.
+func (s *Server) SetTrustedProxies(cidrs ...string) error {
+
+	list := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, str := range cidrs {
+		n, err := parseCIDR(str)
+		if err != nil {
+			return err
+		}
+		list = append(list, n)
+	}
+
+	s.router.trustedProxies = list
+	return nil
+}
+
+func (s *Server) SetDenyList(list *IPList) {
+	s.router.denyList = list
+}
+
+func (s *Server) SetAllowList(list *IPList) {
+	s.router.allowList = list
+}
+
+func (s *Server) SetBlockHandler(fn BlockHandler) {
+	s.router.blockHandler = fn
+}
+
+func (s *Server) SetMaxBodySize(size int64) {
+	s.router.maxBodySize = size
+}
+
+// SetMultipartMemory sets how many bytes of a multipart form are kept in
+// memory by FormFile and FormValue; the rest is stored in temporary files.
+func (s *Server) SetMultipartMemory(size int64) {
+	s.router.formMemory = size
+}
+
+// RegisterEncoder adds or replaces the encoder used by Negotiate for mime.
+// Encoders registered earlier win when the client accepts several equally.
+func (s *Server) RegisterEncoder(mime string, fn EncodeFunc) {
+	s.router.codecs.setEncoder(mime, fn)
+}
+
+func (s *Server) RegisterDecoder(mime string, fn DecodeFunc) {
+	s.router.codecs.decoders[mime] = fn
+}
+
+// SetCompression enables response compression, nil disables it.
+func (s *Server) SetCompression(opts *CompressOptions) {
+	s.router.compression.opts = opts
+}
+
+func (s *Server) RegisterCompressor(name string, fn CompressFunc) {
+	s.router.compression.register(name, fn)
+}
+
+// SetPrecompressed makes Static and File serve file.gz (or file.br when a
+// "br" compressor is registered) instead of file if the client accepts it.
+func (s *Server) SetPrecompressed(enable bool) {
+	s.router.compression.precompressed = enable
+}
+
+func (s *Server) Use(mws ...Middleware) {
+	s.router.middlewares = append(s.router.middlewares, mws...)
+}
+
+// SetSecureHeaders adds the SecureHeaders middleware to all routes and sets
+// the same headers on Static and File responses.
+func (s *Server) SetSecureHeaders(opts *SecureOptions) {
+	if opts == nil {
+		opts = DefaultSecureOptions()
+	}
+	s.router.secure = opts
+	s.Use(SecureHeaders(opts))
+}
+
+// Static serves files from dir under prefix. The returned handler can be
+// configured further; registering the same prefix again replaces it.
+func (s *Server) Static(prefix string, dir string) *StaticHandler {
+	return s.static(prefix, os.DirFS(dir))
+}
+
+// StaticFS serves files from fsys under prefix, e.g. an embed.FS.
+func (s *Server) StaticFS(prefix string, fsys fs.FS) *StaticHandler {
+	return s.static(prefix, fsys)
+}
+
+func (s *Server) static(prefix string, fsys fs.FS) *StaticHandler {
 
 	if !strings.HasSuffix(prefix, "/") && prefix != "" && prefix != "/" {
 		prefix = prefix + "/"
 	}
 
-	handler := http.StripPrefix(prefix, http.FileServer(http.Dir(dir)))
+	sh := newStaticHandler(prefix, fsys, s.router.compression)
 
-	s.router.staticHandlers[prefix] = handler
+	list := s.router.staticHandlers[:0]
+	for _, h := range s.router.staticHandlers {
+		if h.prefix != prefix {
+			list = append(list, h)
+		}
+	}
+
+	s.router.staticHandlers = append(list, sh)
+	sortStatic(s.router.staticHandlers)
+
+	return sh
 }
 
 func (s *Server) File(path string, filename string) {
-	s.router.fileHandlers[path] = &fileHandler{Filename: filename}
+	dir, name := filepath.Split(filename)
+	s.router.fileHandlers[path] = &fileHandler{fs: http.Dir(dir), name: "/" + name, cm: s.router.compression}
+}
+
+// FileFS serves the file name from fsys at path.
+func (s *Server) FileFS(path string, fsys fs.FS, name string) {
+	s.router.fileHandlers[path] = &fileHandler{fs: http.FS(fsys), name: "/" + strings.TrimPrefix(name, "/"), cm: s.router.compression}
 }
 
-func (s *Server) Register(method string, path string, fn Handler) {
+// Register adds fn for method and path. The returned Route can describe the
+// endpoint for the generated OpenAPI document.
+func (s *Server) Register(method string, path string, fn Handler) *Route {
 
 	root, has := s.router.methods[method]
 	if !has {
@@ -107,9 +220,11 @@ func (s *Server) Register(method string, path string, fn Handler) {
 		s.router.methods[method] = root
 	}
 
+	route := s.addRoute(method, path)
+
 	list := path2list(path)
 	if len(list) == 0 {
-		return
+		return route
 	}
 
 	for _, item := range list {
@@ -132,7 +247,7 @@ func (s *Server) Register(method string, path string, fn Handler) {
 			if !h {
 				root.childs[""] = &node{name: "*", fn: fn, wildCard: true}
 			}
-			return
+			return route
 		} else {
 
 			n, h := root.childs[item]
@@ -145,11 +260,12 @@ func (s *Server) Register(method string, path string, fn Handler) {
 	}
 
 	root.fn = fn
-}
 
-func (s *Server) RegisterAuth(method string, path string, fn Handler) {
+	return route
+}
 
-	s.Register(method, path, func(c *Context) {
+func (s *Server) authHandler(fn Handler) Handler {
+	return func(c *Context) {
 
 		if user, login, has := c.BasicAuth(); has {
 			if s.router.authCheck(user, login) {
@@ -161,31 +277,97 @@ func (s *Server) RegisterAuth(method string, path string, fn Handler) {
 		c.SetHeader("WWW-Authenticate", `Basic realm="Enter your login and password"`)
 		c.WriteHeader(http.StatusUnauthorized)
 		c.WriteString("Unauthorized.")
-	})
+	}
 }
 
-func (s *Server) RegMethod(method string, fn interface{}) {
-	s.jrpc.RegMethod(method, fn)
+func (s *Server) RegisterAuth(method string, path string, fn Handler) *Route {
+	route := s.Register(method, path, s.authHandler(fn))
+	route.auth = true
+	return route
 }
 
-func (s *Server) RegisterJsonRPC(url string) {
+func (s *Server) SetWebSocketOptions(opts *WSOptions) {
+	s.router.wsOptions = opts
+}
 
-	s.Register("POST", url, func(c *Context) {
+func (s *Server) WebSocket(path string, fn WSHandler) *Route {
+	return s.Register("GET", path, s.wsHandler(fn))
+}
 
-		out := bytes.NewBuffer(nil)
+func (s *Server) WebSocketAuth(path string, fn WSHandler) *Route {
+	return s.RegisterAuth("GET", path, s.wsHandler(fn))
+}
 
-		if err := s.jrpc.Process(c.Body(), out); err == nil {
-			c.SetContentType("application/json; charset=utf-8")
-			c.WriteHeader(200)
-			c.Write(out.Bytes())
-		} else {
-			c.StandardError(400)
-		}
+// RegMethod registers a JSON-RPC method shared by all endpoints. fn may take
+// *Context as the first argument to reach the HTTP request; mws wrap this
+// method only.
+func (s *Server) RegMethod(method string, fn interface{}, mws ...RPCMiddleware) *RPCMethod {
+	return s.rpc.RegMethod(method, fn, mws...)
+}
 
-	})
+// RegisterJsonRPC serves JSON-RPC at url and returns the endpoint. Methods
+// registered on the endpoint are not visible at other urls. The endpoint also
+// serves the methods registered by RegMethod, as it always has.
+func (s *Server) RegisterJsonRPC(url string) *RPC {
+	rpc := newRPC(s, url, s.rpc)
+	s.Register("POST", url, rpc.Handle).Summary("JSON-RPC 2.0 endpoint").Tags("json-rpc")
+	return rpc
+}
 
+// RegisterJsonRPCAuth serves JSON-RPC behind basic authentication. Unlike
+// RegisterJsonRPC, the endpoint only serves its own methods.
+func (s *Server) RegisterJsonRPCAuth(url string) *RPC {
+	rpc := newRPC(s, url, nil)
+	s.RegisterAuth("POST", url, rpc.Handle).Summary("JSON-RPC 2.0 endpoint").Tags("json-rpc")
+	return rpc
 }
 
+// LoadTemplates makes the tt (jet) engine over dir the default renderer.
 func (s *Server) LoadTemplates(dir string) {
-	s.router.tt = tt.New(dir)
+	s.router.templates.load("", &templateSource{dir: dir, load: func(map[string]interface{}) (Renderer, error) {
+		return NewTTRenderer(dir), nil
+	}})
+}
+
+// LoadTemplatesFS makes the tt (jet) engine over fsys the default renderer.
+func (s *Server) LoadTemplatesFS(fsys fs.FS) {
+	s.router.templates.load("", &templateSource{load: func(map[string]interface{}) (Renderer, error) {
+		return NewTTRendererFS(fsys), nil
+	}})
+}
+
+// LoadHTMLTemplates renders templates with extension ext by html/template
+// parsed from dir. Functions added by AddTemplateFunc are available to them,
+// html/template needs them to be added before the templates are loaded.
+func (s *Server) LoadHTMLTemplates(dir string, ext string) error {
+	return s.router.templates.load(ext, &templateSource{dir: dir, load: func(globals map[string]interface{}) (Renderer, error) {
+		return NewHTMLRenderer(dir, ext, globalFuncs(globals))
+	}})
+}
+
+// LoadHTMLTemplatesFS is LoadHTMLTemplates for templates in fsys.
+func (s *Server) LoadHTMLTemplatesFS(fsys fs.FS, ext string) error {
+	return s.router.templates.load(ext, &templateSource{load: func(globals map[string]interface{}) (Renderer, error) {
+		return NewHTMLRendererFS(fsys, ext, globalFuncs(globals))
+	}})
+}
+
+// SetRenderer registers r for templates with extension ext, "" replaces the default renderer.
+func (s *Server) SetRenderer(ext string, r Renderer) {
+	s.router.templates.set(ext, r)
+}
+
+// SetTemplateReload enables reloading templates when files in the template
+// directory change. It is meant for development.
+func (s *Server) SetTemplateReload(enable bool) {
+	s.router.templates.setReload(enable)
+}
+
+// AddTemplateFunc makes fn callable from every template as name(...).
+func (s *Server) AddTemplateFunc(name string, fn interface{}) {
+	s.router.templates.addGlobal(name, fn)
+}
+
+func (s *Server) AddTemplateVar(name string, value interface{}) {
+	s.router.templates.addGlobal(name, value)
 }
@@ -0,0 +1,325 @@
+package serv
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrStreamClosed    error = errors.New("stream closed")
+	ErrFlushNotSupport error = errors.New("response writer does not support flushing")
+)
+
+type SSEStream struct {
+	c      *Context
+	mu     sync.Mutex
+	done   chan struct{}
+	once   sync.Once
+	lastID string
+}
+
+// SSE switches the response to text/event-stream. The stream is closed
+// when the client disconnects or Close is called.
+func (c *Context) SSE() (*SSEStream, error) {
+
+	if _, ok := c.rw.ResponseWriter.(http.Flusher); !ok {
+		return nil, ErrFlushNotSupport
+	}
+
+	h := c.rw.Header()
+	h.Set("Content-Type", "text/event-stream; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+
+	c.WriteHeader(200)
+	c.rw.Flush()
+
+	st := &SSEStream{
+		c:      c,
+		done:   make(chan struct{}),
+		lastID: c.GetHeader("Last-Event-ID"),
+	}
+
+	go func() {
+		select {
+		case <-c.req.Context().Done():
+			st.Close()
+		case <-st.done:
+		}
+	}()
+
+	return st, nil
+}
+
+// LastEventID returns the id of the last sent event, initially the
+// Last-Event-ID header of a reconnecting client.
+func (st *SSEStream) LastEventID() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.lastID
+}
+
+func (st *SSEStream) Done() <-chan struct{} {
+	return st.done
+}
+
+// Close stops the stream. The handler must close the stream before it
+// returns, so that no keepalive is written into a finished response.
+func (st *SSEStream) Close() {
+	st.mu.Lock()
+	st.stop()
+	st.mu.Unlock()
+}
+
+func (st *SSEStream) stop() {
+	st.once.Do(func() {
+		close(st.done)
+	})
+}
+
+func (st *SSEStream) write(msg string) error {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	select {
+	case <-st.done:
+		return ErrStreamClosed
+	default:
+	}
+
+	if _, err := st.c.rw.Write([]byte(msg)); err != nil {
+		st.stop()
+		return err
+	}
+
+	st.c.rw.Flush()
+
+	return nil
+}
+
+func (st *SSEStream) Send(event string, id string, data string) error {
+
+	var sb strings.Builder
+
+	if event != "" {
+		sb.WriteString("event: " + cleanLine(event) + "\n")
+	}
+
+	if id != "" {
+		sb.WriteString("id: " + cleanLine(id) + "\n")
+	}
+
+	for _, line := range strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+
+	sb.WriteString("\n")
+
+	if err := st.write(sb.String()); err != nil {
+		return err
+	}
+
+	if id != "" {
+		st.mu.Lock()
+		st.lastID = id
+		st.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (st *SSEStream) Retry(d time.Duration) error {
+	return st.write("retry: " + strconv.FormatInt(int64(d/time.Millisecond), 10) + "\n\n")
+}
+
+func (st *SSEStream) Comment(text string) error {
+	return st.write(": " + cleanLine(text) + "\n\n")
+}
+
+// Keepalive sends an empty comment every interval until the stream is closed.
+func (st *SSEStream) Keepalive(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-st.done:
+				return
+			case <-ticker.C:
+				if st.write(":\n\n") != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func cleanLine(str string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(str)
+}
+
+type sseEvent struct {
+	event string
+	id    string
+	data  string
+}
+
+// SSEHub fans events out to subscribed streams. Every subscriber has its own
+// buffer, a subscriber that falls behind by more than the buffer is dropped.
+// The last events with an id are kept for replay to reconnecting clients.
+type SSEHub struct {
+	mu        sync.Mutex
+	subs      map[*SSEStream]chan sseEvent
+	buffer    int
+	history   []sseEvent
+	replay    int
+	keepalive time.Duration
+}
+
+// NewSSEHub creates a hub with the given subscriber buffer (16 when <= 0).
+// By default the hub keeps as many events for replay as the buffer holds and
+// sends a keepalive comment every 30 seconds.
+func NewSSEHub(buffer int) *SSEHub {
+	if buffer <= 0 {
+		buffer = 16
+	}
+	return &SSEHub{
+		subs:      make(map[*SSEStream]chan sseEvent),
+		buffer:    buffer,
+		replay:    buffer,
+		keepalive: 30 * time.Second,
+	}
+}
+
+// SetReplay sets how many events with an id are kept for clients that
+// reconnect with Last-Event-ID, size <= 0 disables replay.
+func (h *SSEHub) SetReplay(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	h.replay = size
+	if len(h.history) > size {
+		h.history = append([]sseEvent(nil), h.history[len(h.history)-size:]...)
+	}
+}
+
+// SetKeepalive sets the keepalive interval of streams opened by Serve,
+// d <= 0 disables keepalive comments.
+func (h *SSEHub) SetKeepalive(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.keepalive = d
+}
+
+func (h *SSEHub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *SSEHub) Publish(event string, id string, data string) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ev := sseEvent{event: event, id: id, data: data}
+
+	if id != "" && h.replay > 0 {
+		if len(h.history) == h.replay {
+			copy(h.history, h.history[1:])
+			h.history = h.history[:len(h.history)-1]
+		}
+		h.history = append(h.history, ev)
+	}
+
+	for st, ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, st)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers st and returns its channel together with the events
+// published after lastID. An id that is no longer in the history replays
+// everything that is kept.
+func (h *SSEHub) subscribe(st *SSEStream, lastID string) (chan sseEvent, []sseEvent, time.Duration) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan sseEvent, h.buffer)
+	h.subs[st] = ch
+
+	var missed []sseEvent
+
+	if lastID != "" {
+		start := 0
+		for i := len(h.history) - 1; i >= 0; i-- {
+			if h.history[i].id == lastID {
+				start = i + 1
+				break
+			}
+		}
+		missed = append(missed, h.history[start:]...)
+	}
+
+	return ch, missed, h.keepalive
+}
+
+func (h *SSEHub) unsubscribe(st *SSEStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ch, has := h.subs[st]; has {
+		delete(h.subs, st)
+		close(ch)
+	}
+}
+
+// Serve opens an event stream for c, replays the events missed since the
+// Last-Event-ID of the request and forwards published events to it until the
+// client goes away. It can be used directly as a Handler.
+func (h *SSEHub) Serve(c *Context) {
+
+	st, err := c.SSE()
+	if err != nil {
+		c.StandardError(500)
+		return
+	}
+	defer st.Close()
+
+	ch, missed, keepalive := h.subscribe(st, st.LastEventID())
+	defer h.unsubscribe(st)
+
+	for _, ev := range missed {
+		if st.Send(ev.event, ev.id, ev.data) != nil {
+			return
+		}
+	}
+
+	if keepalive > 0 {
+		st.Keepalive(keepalive)
+	}
+
+	for {
+		select {
+		case <-st.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok || st.Send(ev.event, ev.id, ev.data) != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package serv
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+
+	s := New()
+	hub := NewSSEHub(4)
+
+	s.Register("GET", "/events", hub.Serve)
+
+	ts := httptest.NewServer(s.router)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req = req.WithContext(ctx)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Type") != "text/event-stream; charset=utf-8" {
+		t.Fatal("invalid content type")
+	}
+
+	for i := 0; hub.Len() == 0; i++ {
+		if i > 100 {
+			t.Fatal("subscriber not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hub.Publish("update", "7", "line1\nline2")
+
+	br := bufio.NewReader(res.Body)
+
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "\n" {
+			break
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	if strings.Join(lines, "|") != "event: update|id: 7|data: line1|data: line2" {
+		t.Fatalf("invalid event: %v", lines)
+	}
+
+	cancel()
+
+	for i := 0; hub.Len() != 0; i++ {
+		if i > 100 {
+			t.Fatal("subscriber not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSSEReplay(t *testing.T) {
+
+	s := New()
+	hub := NewSSEHub(4)
+	hub.SetReplay(2)
+	hub.SetKeepalive(20 * time.Millisecond)
+
+	s.Register("GET", "/events", hub.Serve)
+
+	ts := httptest.NewServer(s.router)
+	defer ts.Close()
+
+	hub.Publish("update", "1", "a")
+	hub.Publish("update", "2", "b")
+	hub.Publish("update", "3", "c")
+	hub.Publish("update", "", "no id")
+
+	tR := func(lastID string, wait string) {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+		req = req.WithContext(ctx)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		br := bufio.NewReader(res.Body)
+
+		var lines []string
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimSpace(line)
+			if line == ":" {
+				break
+			}
+			if strings.HasPrefix(line, "id: ") {
+				lines = append(lines, line[4:])
+			}
+		}
+
+		if strings.Join(lines, ",") != wait {
+			t.Fatalf("Last-Event-ID %q replays %v wait %s", lastID, lines, wait)
+		}
+	}
+
+	tR("", "")
+	tR("2", "3")
+	tR("3", "")
+	tR("1", "2,3")
+}
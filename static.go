@@ -0,0 +1,207 @@
+package serv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+type etagEntry struct {
+	modTime time.Time
+	size    int64
+	tag     string
+}
+
+// StaticHandler serves files under a prefix. Directory listing is off by
+// default, names produced by SetFingerprint are cached as immutable.
+type StaticHandler struct {
+	prefix  string
+	fs      http.FileSystem
+	fsys    fs.FS
+	cm      *compression
+	listing bool
+	etag    bool
+	spa     bool
+	cache   map[string]string
+	mu      sync.Mutex
+	etags   map[string]etagEntry
+	assets  *assets
+}
+
+func newStaticHandler(prefix string, fsys fs.FS, cm *compression) *StaticHandler {
+	return &StaticHandler{
+		prefix: prefix,
+		fs:     http.FS(fsys),
+		fsys:   fsys,
+		cm:     cm,
+		cache:  make(map[string]string),
+		etags:  make(map[string]etagEntry),
+	}
+}
+
+func (sh *StaticHandler) SetListing(enable bool) *StaticHandler {
+	sh.listing = enable
+	return sh
+}
+
+// SetCacheControl sets the Cache-Control header for files with extension
+// ext, "" sets the default for all files.
+func (sh *StaticHandler) SetCacheControl(ext string, value string) *StaticHandler {
+	sh.cache[normExt(ext)] = value
+	return sh
+}
+
+// SetETag enables strong ETags computed from the file content.
+func (sh *StaticHandler) SetETag(enable bool) *StaticHandler {
+	sh.etag = enable
+	return sh
+}
+
+// SetSPA serves /index.html for unknown paths without an extension, so that
+// a single page application can handle its own routes.
+func (sh *StaticHandler) SetSPA(enable bool) *StaticHandler {
+	sh.spa = enable
+	return sh
+}
+
+func (sh *StaticHandler) cacheControl(name string) string {
+
+	if v, has := sh.cache[path.Ext(name)]; has {
+		return v
+	}
+
+	return sh.cache[""]
+}
+
+func (sh *StaticHandler) open(name string) (http.File, os.FileInfo, error) {
+
+	f, err := sh.fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, st, nil
+}
+
+// contentETag returns the strong ETag of the file, cached by modification time and size.
+func (sh *StaticHandler) contentETag(name string, f http.File, st os.FileInfo) string {
+
+	sh.mu.Lock()
+	e, has := sh.etags[name]
+	sh.mu.Unlock()
+
+	if has && e.modTime.Equal(st.ModTime()) && e.size == st.Size() {
+		return e.tag
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+
+	tag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+
+	sh.mu.Lock()
+	sh.etags[name] = etagEntry{modTime: st.ModTime(), size: st.Size(), tag: tag}
+	sh.mu.Unlock()
+
+	return tag
+}
+
+func (sh *StaticHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+
+	name := path.Clean("/" + strings.TrimPrefix(req.URL.Path, sh.prefix))
+	cc := ""
+
+	if sh.assets != nil {
+		if orig, has := sh.assets.original(name[1:]); has {
+			name = "/" + orig
+			cc = immutableCacheControl
+		}
+	}
+
+	f, st, err := sh.open(name)
+
+	if err == nil && st.IsDir() {
+		f.Close()
+
+		if !strings.HasSuffix(req.URL.Path, "/") {
+			http.Redirect(rw, req, path.Base(req.URL.Path)+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		if sh.listing {
+			idx, _, err := sh.open(path.Join(name, "index.html"))
+			if err != nil {
+				http.StripPrefix(sh.prefix, http.FileServer(sh.fs)).ServeHTTP(rw, req)
+				return
+			}
+			idx.Close()
+		}
+
+		name = path.Join(name, "index.html")
+		f, st, err = sh.open(name)
+	}
+
+	if err != nil && sh.spa && path.Ext(name) == "" {
+		name = "/index.html"
+		f, st, err = sh.open(name)
+	}
+
+	if err != nil || st.IsDir() {
+		if err == nil {
+			f.Close()
+		}
+		http.NotFound(rw, req)
+		return
+	}
+
+	defer f.Close()
+
+	if cc == "" {
+		cc = sh.cacheControl(name)
+	}
+
+	if cc != "" {
+		rw.Header().Set("Cache-Control", cc)
+	}
+
+	if sh.cm.servePrecompressed(rw, req, sh.fs, name) {
+		return
+	}
+
+	if sh.etag {
+		if tag := sh.contentETag(name, f, st); tag != "" {
+			rw.Header().Set("ETag", tag)
+		}
+	}
+
+	http.ServeContent(rw, req, st.Name(), st.ModTime(), f)
+}
+
+// sortStatic orders handlers by prefix length, so the longest prefix matches first.
+func sortStatic(list []*StaticHandler) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return len(list[i].prefix) > len(list[j].prefix)
+	})
+}
@@ -0,0 +1,156 @@
+package serv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticHandler(t *testing.T) {
+
+	assets := fstest.MapFS{
+		"app.js":             {Data: []byte("app")},
+		"app.3f2a1c9e.js":    {Data: []byte("app v1")},
+		"style.css":          {Data: []byte("body{}")},
+		"docs/readme.txt":    {Data: []byte("readme")},
+		"vendor/lib.js":      {Data: []byte("lib")},
+		"spa/index.html":     {Data: []byte("<app>")},
+		"spa/assets/main.js": {Data: []byte("main")},
+	}
+
+	vendor := fstest.MapFS{
+		"lib.js": {Data: []byte("vendor lib")},
+	}
+
+	spa, _ := assets.Sub("spa")
+
+	s := New()
+
+	s.StaticFS("/static", assets).
+		SetCacheControl(".css", "public, max-age=3600").
+		SetCacheControl("", "no-cache").
+		SetETag(true)
+
+	s.StaticFS("/static/vendor", vendor)
+	s.StaticFS("/list", assets).SetListing(true)
+	s.StaticFS("/app", spa).SetSPA(true)
+
+	get := func(url string, hdr map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	rw := get("/static/vendor/lib.js", nil)
+	if rw.Body.String() != "vendor lib" {
+		t.Fatalf("longest prefix must win: %q", rw.Body.String())
+	}
+
+	rw = get("/static/style.css", nil)
+	if rw.Code != 200 || rw.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Fatalf("invalid css cache: %d %q", rw.Code, rw.Header().Get("Cache-Control"))
+	}
+
+	rw = get("/static/app.js", nil)
+	etag := rw.Header().Get("ETag")
+	if rw.Header().Get("Cache-Control") != "no-cache" || len(etag) != 34 {
+		t.Fatalf("invalid headers: %v", rw.Header())
+	}
+
+	if rw = get("/static/app.js", map[string]string{"If-None-Match": etag}); rw.Code != 304 {
+		t.Fatalf("expected 304, got %d", rw.Code)
+	}
+
+	rw = get("/static/app.3f2a1c9e.js", nil)
+	if rw.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("hex in a name must not make it immutable: %q", rw.Header().Get("Cache-Control"))
+	}
+
+	if rw = get("/static/docs/", nil); rw.Code != 404 {
+		t.Fatalf("listing must be disabled: %d", rw.Code)
+	}
+
+	if rw = get("/list/docs/", nil); rw.Code != 200 || !strings.Contains(rw.Body.String(), "readme.txt") {
+		t.Fatalf("listing must be enabled: %d %q", rw.Code, rw.Body.String())
+	}
+
+	if rw = get("/app/users/12", nil); rw.Code != 200 || rw.Body.String() != "<app>" {
+		t.Fatalf("invalid spa fallback: %d %q", rw.Code, rw.Body.String())
+	}
+
+	if rw = get("/app/assets/none.js", nil); rw.Code != 404 {
+		t.Fatalf("missing assets must not fall back: %d", rw.Code)
+	}
+
+	if rw = get("/app/assets/main.js", nil); rw.Body.String() != "main" {
+		t.Fatalf("invalid spa asset: %q", rw.Body.String())
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "serv-assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "js"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("v1"), 0644)
+
+	s := New()
+	sh := s.Static("/static", dir).SetFingerprint(true, true)
+	sh.assets.interval = 0
+
+	s.Register("GET", "/page", func(c *Context) {
+		c.RenderStr(`<script src="{{ asset("js/app.js") }}"></script>`, nil)
+	})
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, httptest.NewRequest("GET", url, nil))
+		return rw
+	}
+
+	fpName := regexp.MustCompile(`\.[0-9a-f]{8}\.js$`)
+
+	url := s.Asset("/static/js/app.js")
+	if !fpName.MatchString(url) || !strings.HasPrefix(url, "/static/js/app.") {
+		t.Fatalf("invalid asset url: %s", url)
+	}
+
+	if rw := get("/page"); rw.Body.String() != `<script src="`+url+`"></script>` {
+		t.Fatalf("invalid template helper: %q", rw.Body.String())
+	}
+
+	rw := get(url)
+	if rw.Code != 200 || rw.Body.String() != "v1" || rw.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Fatalf("invalid fingerprinted response: %d %q", rw.Code, rw.Body.String())
+	}
+
+	var manifest map[string]string
+	buf := bytes.NewBuffer(nil)
+	if err := sh.WriteManifest(buf); err != nil || json.Unmarshal(buf.Bytes(), &manifest) != nil || "/static/"+manifest["js/app.js"] != url {
+		t.Fatalf("invalid manifest: %s", buf.String())
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("v2"), 0644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "js", "app.js"), future, future)
+
+	if next := s.Asset("js/app.js"); next == url || !fpName.MatchString(next) {
+		t.Fatalf("asset not rehashed: %s", next)
+	}
+}
@@ -0,0 +1,136 @@
+package serv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+var ErrClientGone error = errors.New("client gone")
+
+func (c *Context) Flush() {
+	c.rw.Flush()
+}
+
+func (c *Context) ClientGone() bool {
+	select {
+	case <-c.req.Context().Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Stream calls step until it returns false or the client disconnects and
+// flushes the response after every call. It reports whether the client went away.
+// Writes block while the client is not reading, so a slow client slows down step.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	for {
+		if c.ClientGone() {
+			return true
+		}
+
+		more := step(c.rw)
+		c.Flush()
+
+		if !more {
+			return c.ClientGone()
+		}
+	}
+}
+
+// JSONStream encodes values one by one as NDJSON lines or JSON array items.
+// Output is buffered and flushed when the buffer fills up, every FlushEvery
+// values (if set) and on Close.
+type JSONStream struct {
+	FlushEvery int
+
+	c      *Context
+	bw     *bufio.Writer
+	enc    *json.Encoder
+	array  bool
+	count  int
+	closed bool
+}
+
+func (c *Context) newJSONStream(contentType string, array bool) *JSONStream {
+
+	c.SetContentType(contentType)
+	c.WriteHeader(200)
+
+	js := &JSONStream{c: c, bw: bufio.NewWriterSize(c.rw, 32<<10), array: array}
+	js.enc = json.NewEncoder(js.bw)
+
+	if array {
+		js.bw.WriteByte('[')
+	}
+
+	return js
+}
+
+func (c *Context) NDJSON() *JSONStream {
+	return c.newJSONStream("application/x-ndjson", false)
+}
+
+func (c *Context) JSONArray() *JSONStream {
+	return c.newJSONStream("application/json; charset=utf-8", true)
+}
+
+func (js *JSONStream) Encode(v interface{}) error {
+
+	if js.closed {
+		return ErrStreamClosed
+	}
+
+	if js.c.ClientGone() {
+		return ErrClientGone
+	}
+
+	if js.array && js.count > 0 {
+		if err := js.bw.WriteByte(','); err != nil {
+			return err
+		}
+	}
+
+	if err := js.enc.Encode(v); err != nil {
+		return err
+	}
+
+	js.count++
+
+	if js.FlushEvery > 0 && js.count%js.FlushEvery == 0 {
+		return js.Flush()
+	}
+
+	return nil
+}
+
+func (js *JSONStream) Count() int {
+	return js.count
+}
+
+func (js *JSONStream) Flush() error {
+	if err := js.bw.Flush(); err != nil {
+		return err
+	}
+	js.c.Flush()
+	return nil
+}
+
+func (js *JSONStream) Close() error {
+
+	if js.closed {
+		return nil
+	}
+
+	js.closed = true
+
+	if js.array {
+		if err := js.bw.WriteByte(']'); err != nil {
+			return err
+		}
+	}
+
+	return js.Flush()
+}
@@ -0,0 +1,75 @@
+package serv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+
+	s := New()
+
+	gone := false
+
+	s.Register("GET", "/count", func(c *Context) {
+		i := 0
+		gone = c.Stream(func(w io.Writer) bool {
+			i++
+			fmt.Fprintf(w, "%d;", i)
+			return i < 3
+		})
+	})
+
+	s.Register("GET", "/ndjson", func(c *Context) {
+		js := c.NDJSON()
+		js.FlushEvery = 2
+		for i := 0; i < 3; i++ {
+			js.Encode(map[string]int{"n": i})
+		}
+		js.Close()
+	})
+
+	s.Register("GET", "/array", func(c *Context) {
+		js := c.JSONArray()
+		for i := 0; i < 3; i++ {
+			js.Encode(i)
+		}
+		js.Close()
+	})
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/count", nil))
+
+	if rw.Body.String() != "1;2;3;" || !rw.Flushed || gone {
+		t.Fatalf("invalid stream: %q", rw.Body.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/count", nil).WithContext(ctx))
+
+	if rw.Body.String() != "" || !gone {
+		t.Fatal("stream must stop for gone client")
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/ndjson", nil))
+
+	if rw.Body.String() != "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n" || rw.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("invalid ndjson: %q", rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/array", nil))
+
+	var list []int
+	if err := json.Unmarshal(rw.Body.Bytes(), &list); err != nil || len(list) != 3 || list[2] != 2 {
+		t.Fatalf("invalid array: %q", rw.Body.String())
+	}
+}
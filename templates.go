@@ -0,0 +1,247 @@
+package serv
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wmentor/tt"
+)
+
+const defaultReloadInterval = time.Second
+
+var ErrNoRenderer error = errors.New("no renderer for template")
+
+// templateSource rebuilds a renderer, so that it can be reloaded when
+// template functions are added or files in dir change. Sources without dir
+// (e.g. embedded files) are never checked for changes.
+type templateSource struct {
+	dir  string
+	load func(globals map[string]interface{}) (Renderer, error)
+}
+
+// templates keeps renderers by file extension ("" is the default one) and
+// the globals added to every render. In reload mode renderers loaded from
+// directories are rebuilt when a file changes; directories are scanned at
+// most once per interval.
+type templates struct {
+	mu        sync.RWMutex
+	renderers map[string]Renderer
+	sources   map[string]*templateSource
+	reload    bool
+	interval  time.Duration
+	checked   time.Time
+	modTime   time.Time
+	dirty     bool
+	loadErrs  map[string]error
+	globals   map[string]interface{}
+}
+
+func newTemplates() *templates {
+	return &templates{
+		renderers: map[string]Renderer{"": NewTTRenderer()},
+		sources:   make(map[string]*templateSource),
+		loadErrs:  make(map[string]error),
+		interval:  defaultReloadInterval,
+		globals:   make(map[string]interface{}),
+	}
+}
+
+func normExt(ext string) string {
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func (t *templates) set(ext string, r Renderer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ext = normExt(ext)
+	t.renderers[ext] = r
+	delete(t.sources, ext)
+	delete(t.loadErrs, ext)
+}
+
+func (t *templates) load(ext string, src *templateSource) error {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, err := src.load(t.globals)
+	if err != nil {
+		return err
+	}
+
+	ext = normExt(ext)
+	t.renderers[ext] = r
+	t.sources[ext] = src
+	delete(t.loadErrs, ext)
+	t.modTime = t.sourcesModTime()
+	t.checked = time.Now()
+
+	return nil
+}
+
+func (t *templates) setReload(enable bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reload = enable
+}
+
+func (t *templates) addGlobal(name string, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.globals[name] = value
+	t.dirty = true
+}
+
+func (t *templates) sourcesModTime() time.Time {
+	var res time.Time
+	for _, src := range t.sources {
+		if src.dir == "" {
+			continue
+		}
+		if mt := dirModTime(src.dir); mt.After(res) {
+			res = mt
+		}
+	}
+	return res
+}
+
+// refresh rebuilds directory renderers after globals were added or, in
+// reload mode, when their files have changed.
+func (t *templates) refresh() {
+
+	t.mu.RLock()
+	check := t.dirty || t.reload && len(t.sources) > 0 && time.Since(t.checked) >= t.interval
+	t.mu.RUnlock()
+
+	if !check {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.dirty {
+		if time.Since(t.checked) < t.interval {
+			return
+		}
+
+		t.checked = time.Now()
+
+		mt := t.sourcesModTime()
+		if mt.Equal(t.modTime) {
+			return
+		}
+		t.modTime = mt
+	}
+
+	t.dirty = false
+
+	for ext, src := range t.sources {
+		r, err := src.load(t.globals)
+		if err != nil {
+			t.loadErrs[ext] = err
+			continue
+		}
+		t.renderers[ext] = r
+		delete(t.loadErrs, ext)
+	}
+}
+
+// renderer picks the renderer registered for the extension of name and
+// falls back to the default one. A renderer whose last reload failed
+// returns that error, other renderers keep working.
+func (t *templates) renderer(name string) (Renderer, error) {
+
+	t.refresh()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, ext := range []string{path.Ext(name), ""} {
+		if r, has := t.renderers[ext]; has {
+			if err := t.loadErrs[ext]; err != nil {
+				return nil, err
+			}
+			return r, nil
+		}
+	}
+
+	return nil, ErrNoRenderer
+}
+
+func (t *templates) render(name string, vars map[string]interface{}) ([]byte, error) {
+
+	r, err := t.renderer(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Render(name, vars)
+}
+
+func (t *templates) makeVars() map[string]interface{} {
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	v := make(map[string]interface{}, len(t.globals))
+	for k, val := range t.globals {
+		v[k] = val
+	}
+
+	return v
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// globalFuncs picks the globals usable as html/template functions: they
+// return one value or a value and an error.
+func globalFuncs(globals map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	for k, v := range globals {
+		if v == nil {
+			continue
+		}
+		ft := reflect.TypeOf(v)
+		if ft.Kind() != reflect.Func {
+			continue
+		}
+		if ft.NumOut() == 1 || ft.NumOut() == 2 && ft.Out(1) == errorType {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func ttVars(vars map[string]interface{}) tt.Vars {
+	v := tt.MakeVars()
+	for k, val := range vars {
+		v.Set(k, val)
+	}
+	return v
+}
+
+// dirModTime returns the latest modification time of dir and everything
+// below it. Directories are included so that removed files are noticed too.
+func dirModTime(dir string) time.Time {
+
+	var res time.Time
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.ModTime().After(res) {
+			res = info.ModTime()
+		}
+		return nil
+	})
+
+	return res
+}
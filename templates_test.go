@@ -0,0 +1,145 @@
+package serv
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplates(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "serv-tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, body string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("layout.jet", `<main>{{ body | raw }}</main>`)
+	write("page.jet", `{{ upper(name) }} @ {{ site }}`)
+
+	s := New()
+	s.LoadTemplates(dir)
+	s.SetTemplateReload(true)
+	s.router.templates.interval = 0
+	s.AddTemplateFunc("upper", strings.ToUpper)
+	s.AddTemplateVar("site", "example")
+
+	var renderErr error
+	s.SetErrorHandler(func(err error) {
+		renderErr = err
+	})
+
+	s.Register("GET", "/page", func(c *Context) {
+		c.RenderLayout("layout.jet", "page.jet", map[string]interface{}{"name": "<bob>"})
+	})
+
+	s.Register("GET", "/missing", func(c *Context) {
+		c.Render("missing.jet", nil)
+	})
+
+	get := func(url string) (int, string) {
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, httptest.NewRequest("GET", url, nil))
+		return rw.Code, rw.Body.String()
+	}
+
+	if code, body := get("/page"); code != 200 || body != "<main>&lt;BOB&gt; @ example</main>" {
+		t.Fatalf("invalid layout: %d %q %v", code, body, renderErr)
+	}
+
+	if code, _ := get("/missing"); code != 500 || renderErr == nil {
+		t.Fatal("render errors must produce 500")
+	}
+
+	write("page.jet", `changed`)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "page.jet"), future, future)
+
+	if code, body := get("/page"); code != 200 || body != "<main>changed</main>" {
+		t.Fatalf("template not reloaded: %d %q", code, body)
+	}
+}
+
+type testRenderer struct{}
+
+func (testRenderer) Render(name string, vars map[string]interface{}) ([]byte, error) {
+	return []byte("text:" + name), nil
+}
+
+func TestRenderers(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "serv-html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "parts"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "layout.html"), []byte(`<body>{{ .body }}</body>`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(`{{ template "parts/name.html" . }} {{ upper .site }}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "parts", "name.html"), []byte(`<b>{{ .name }}</b>`), 0644)
+
+	s := New()
+
+	s.AddTemplateFunc("upper", strings.ToUpper)
+	s.AddTemplateVar("site", "example")
+
+	if err := s.LoadHTMLTemplates(dir, "html"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetRenderer(".txt", testRenderer{})
+
+	s.Register("GET", "/html", func(c *Context) {
+		c.RenderLayout("layout.html", "page.html", map[string]interface{}{"name": "<bob>"})
+	})
+
+	s.Register("GET", "/txt", func(c *Context) {
+		c.Render("hello.txt", nil)
+	})
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/html", nil))
+
+	if rw.Code != 200 || rw.Body.String() != "<body><b>&lt;bob&gt;</b> EXAMPLE</body>" {
+		t.Fatalf("invalid html: %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/txt", nil))
+
+	if rw.Body.String() != "text:hello.txt" {
+		t.Fatalf("invalid renderer: %q", rw.Body.String())
+	}
+
+	s.SetTemplateReload(true)
+	s.router.templates.interval = 0
+
+	ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(`{{ .name `), 0644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "page.html"), future, future)
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/html", nil))
+
+	if rw.Code != 500 {
+		t.Fatalf("broken template must fail: %d", rw.Code)
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/txt", nil))
+
+	if rw.Code != 200 || rw.Body.String() != "text:hello.txt" {
+		t.Fatalf("load error leaks to other renderers: %d %q", rw.Code, rw.Body.String())
+	}
+}
@@ -0,0 +1,186 @@
+package serv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutWriter buffers the response of a handler running under Timeout.
+// Flush commits the buffered response to w and later writes go straight
+// through. Once the deadline has passed, writes fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	w        *responseWriter
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	flushed  bool
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 && !w.expired() {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.flushed {
+		return w.w.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired() {
+		return
+	}
+	w.commit()
+	w.flushed = true
+	w.w.Flush()
+}
+
+// expired reports whether the deadline has passed. The handler may see the
+// cancelled context before Timeout marks the writer, so the context is
+// checked as well.
+func (w *timeoutWriter) expired() bool {
+	return w.timedOut || w.ctx.Err() == context.DeadlineExceeded
+}
+
+// commit copies the buffered headers, status and body to w. It must be
+// called with mu held.
+func (w *timeoutWriter) commit() {
+
+	h := w.w.Header()
+	for k, v := range w.header {
+		h[k] = v
+	}
+
+	if w.status != 0 {
+		w.w.WriteHeader(w.status)
+	}
+
+	if w.buf.Len() > 0 {
+		w.w.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// Timeout limits the route to d. The handler gets a context cancelled at the
+// deadline and, if it has not finished by then, the client gets status
+// (503 when status is 0) and everything the handler writes afterwards is
+// dropped. The response is buffered until the handler flushes it. After a
+// flush the status is already sent, so the deadline only cancels the context
+// and stops further writes.
+func Timeout(d time.Duration, status int) Middleware {
+
+	if status <= 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+
+			ctx, cancel := context.WithTimeout(c.req.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{ctx: ctx, w: c.rw, header: make(http.Header)}
+
+			for k, v := range c.rw.Header() {
+				tw.header[k] = v
+			}
+
+			tc := *c
+			tc.rw = newResponseWriter(tw)
+			tc.req = c.req.WithContext(ctx)
+
+			if lb, ok := c.req.Body.(*limitedBody); ok {
+				tc.req.Body = &limitedBody{c: &tc, rc: lb.rc, limit: lb.limit}
+			}
+
+			if c.values != nil {
+				tc.values = make(map[string]interface{}, len(c.values))
+				for k, v := range c.values {
+					tc.values[k] = v
+				}
+			}
+
+			done := make(chan struct{})
+			panicChan := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if re := recover(); re != nil {
+						tw.mu.Lock()
+						late := tw.timedOut
+						if !late {
+							panicChan <- re
+						}
+						tw.mu.Unlock()
+
+						// Nobody waits for the handler after the deadline,
+						// so a late panic is only reported.
+						if late && tc.errorHandler != nil {
+							tc.errorHandler(fmt.Errorf("%s %s: panic after timeout: %v", tc.req.Method, tc.req.URL.Path, re))
+						}
+					}
+				}()
+				next(&tc)
+				close(done)
+			}()
+
+			select {
+			case re := <-panicChan:
+				panic(re)
+
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				c.bodyTooLarge = c.bodyTooLarge || tc.bodyTooLarge
+				c.values = tc.values
+
+				if !tw.flushed {
+					tw.commit()
+				}
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				flushed := tw.flushed
+				tw.mu.Unlock()
+
+				select {
+				case re := <-panicChan:
+					panic(re)
+				default:
+				}
+
+				if !flushed && ctx.Err() == context.DeadlineExceeded {
+					c.StandardError(status)
+				}
+			}
+		}
+	}
+}
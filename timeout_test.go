@@ -0,0 +1,166 @@
+package serv
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+
+	s := New()
+
+	s.Use(func(next Handler) Handler {
+		return func(c *Context) {
+			c.Set("user", "alice")
+			next(c)
+		}
+	})
+
+	slow := make(chan struct{})
+
+	s.Register("GET", "/fast", Timeout(time.Second, 0)(func(c *Context) {
+		user, _ := c.Get("user")
+		c.SetHeader("X-User", user.(string))
+		c.WriteHeader(201)
+		c.WriteString("ok")
+	}))
+
+	s.Register("GET", "/slow", Timeout(20*time.Millisecond, 504)(func(c *Context) {
+		<-c.Context().Done()
+		c.WriteString("late")
+		close(slow)
+	}))
+
+	s.Register("GET", "/panic", Timeout(time.Second, 0)(func(c *Context) {
+		panic("boom")
+	}))
+
+	s.Register("GET", "/flush", Timeout(20*time.Millisecond, 0)(func(c *Context) {
+		c.WriteString("first")
+		c.Flush()
+		<-c.Context().Done()
+		c.WriteString("late")
+		close(slow)
+	}))
+
+	s.Register("GET", "/set", Timeout(time.Second, 0)(func(c *Context) {
+		c.Set("role", "admin")
+	}))
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/fast", nil))
+
+	if rw.Code != 201 || rw.Body.String() != "ok" || rw.Header().Get("X-User") != "alice" {
+		t.Fatalf("invalid response: %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/slow", nil))
+	<-slow
+
+	if rw.Code != 504 || rw.Body.String() != "504 Gateway Timeout" {
+		t.Fatalf("invalid timeout response: %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/panic", nil))
+
+	if rw.Code != 500 {
+		t.Fatalf("panic must reach the router: %d", rw.Code)
+	}
+
+	slow = make(chan struct{})
+
+	rw = httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/flush", nil))
+	<-slow
+
+	if rw.Code != 200 || rw.Body.String() != "first" || !rw.Flushed {
+		t.Fatalf("invalid flushed response: %d %q", rw.Code, rw.Body.String())
+	}
+
+	var role interface{}
+
+	s.Use(func(next Handler) Handler {
+		return func(c *Context) {
+			next(c)
+			role, _ = c.Get("role")
+		}
+	})
+
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/set", nil))
+
+	if role != "admin" {
+		t.Fatal("values set under Timeout are lost")
+	}
+}
+
+func TestTimeoutBodyLimit(t *testing.T) {
+
+	s := New()
+	s.SetMaxBodySize(16)
+
+	s.Register("POST", "/json", Timeout(time.Second, 0)(func(c *Context) {
+		var v interface{}
+		if err := c.BodyJson(&v); err != nil {
+			c.WriteError(err)
+			return
+		}
+		c.WriteHeader(200)
+	}))
+
+	tP := func(body string, chunked bool, code int) {
+
+		req := httptest.NewRequest("POST", "/json", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code {
+			t.Fatalf("POST %d bytes returns %d wait %d", len(body), rw.Code, code)
+		}
+	}
+
+	long := `"` + strings.Repeat("x", 100) + `"`
+
+	tP(`{"a":1}`, false, 200)
+	tP(long, false, 413)
+	tP(long, true, 413)
+}
+
+func TestTimeoutLatePanic(t *testing.T) {
+
+	s := New()
+
+	reported := make(chan error, 1)
+	s.SetErrorHandler(func(err error) {
+		reported <- err
+	})
+
+	s.Register("GET", "/late", Timeout(20*time.Millisecond, 0)(func(c *Context) {
+		<-c.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		panic("late boom")
+	}))
+
+	rw := httptest.NewRecorder()
+	s.router.ServeHTTP(rw, httptest.NewRequest("GET", "/late", nil))
+
+	if rw.Code != 503 {
+		t.Fatalf("invalid timeout response: %d", rw.Code)
+	}
+
+	select {
+	case err := <-reported:
+		if !strings.Contains(err.Error(), "late boom") {
+			t.Fatalf("invalid report: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("late panic not reported")
+	}
+}
@@ -0,0 +1,106 @@
+package serv
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// HTTPError is an error that knows the HTTP status it should be reported with.
+type HTTPError interface {
+	error
+	StatusCode() int
+}
+
+// JSON adapts a typed function to a Handler. The input is bound and
+// validated like BindValid (struct types) or decoded from the body (other
+// types), a pointer to a struct gets a fresh struct. The result is written
+// as JSON with status 200. Errors go through WriteError, so HTTPError values
+// keep their status.
+func JSON[In, Out any](fn func(c *Context, in In) (Out, error)) Handler {
+	return func(c *Context) {
+
+		in, err := bindTyped[In](c)
+		if err != nil {
+			c.WriteError(err)
+			return
+		}
+
+		out, err := fn(c, in)
+		if err != nil {
+			c.WriteError(err)
+			return
+		}
+
+		if c.rw.written {
+			return
+		}
+
+		c.sendJSON(out)
+	}
+}
+
+// Registrar is implemented by Server and RouteGroup.
+type Registrar interface {
+	Register(method string, path string, fn Handler) *Route
+}
+
+// RegisterJSON registers JSON(fn) on r and records In and Out as the input
+// and output of the route for generated documents.
+func RegisterJSON[In, Out any](r Registrar, method string, path string, fn func(c *Context, in In) (Out, error)) *Route {
+	return r.Register(method, path, JSON(fn)).
+		Input(reflect.TypeOf((*In)(nil)).Elem()).
+		Output(reflect.TypeOf((*Out)(nil)).Elem())
+}
+
+// sendJSON encodes v before the status is sent, so an encoding error still
+// gets a clean error reply.
+func (c *Context) sendJSON(v interface{}) {
+
+	buf := bytes.NewBuffer(nil)
+
+	if err := encodeJSON(buf, v); err != nil {
+		c.WriteError(err)
+		return
+	}
+
+	c.SetContentType("application/json; charset=utf-8")
+	c.WriteHeader(200)
+	c.Write(buf.Bytes())
+}
+
+func bindTyped[In any](c *Context) (In, error) {
+
+	var in In
+
+	if t := reflect.TypeOf(&in).Elem(); t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		pv := reflect.New(t.Elem())
+		if err := c.bindInput(pv.Interface()); err != nil {
+			return in, err
+		}
+		return pv.Interface().(In), nil
+	}
+
+	err := c.bindInput(&in)
+
+	return in, err
+}
+
+func (c *Context) bindInput(dst interface{}) error {
+
+	if reflect.TypeOf(dst).Elem().Kind() == reflect.Struct {
+		return c.BindValid(dst)
+	}
+
+	if c.req.Body == nil || c.req.ContentLength == 0 {
+		return nil
+	}
+
+	if err := c.Decode(dst); err != nil {
+		if err == ErrBodyTooLarge || err == ErrUnsupportedMediaType {
+			return err
+		}
+		return BindErrors{jsonFieldError(err)}
+	}
+
+	return Validate(dst)
+}
@@ -0,0 +1,72 @@
+package serv
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testStatusError struct{}
+
+func (testStatusError) Error() string   { return "item is locked" }
+func (testStatusError) StatusCode() int { return 409 }
+
+func TestJSON(t *testing.T) {
+
+	type input struct {
+		ID   int    `path:"id"`
+		Name string `json:"name" validate:"required"`
+	}
+
+	type output struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	s := New()
+
+	s.Register("PUT", "/items/:id", JSON(func(c *Context, in input) (*output, error) {
+		if in.ID == 13 {
+			return nil, testStatusError{}
+		}
+		return &output{ID: in.ID, Name: in.Name}, nil
+	}))
+
+	s.Register("POST", "/sum", JSON(func(c *Context, in []int) (int, error) {
+		sum := 0
+		for _, v := range in {
+			sum += v
+		}
+		return sum, nil
+	}))
+
+	s.Register("POST", "/items", JSON(func(c *Context, in *input) (*output, error) {
+		return &output{ID: 1, Name: in.Name}, nil
+	}))
+
+	s.Register("GET", "/bad", JSON(func(c *Context, in struct{}) (func(), error) {
+		return func() {}, nil
+	}))
+
+	tJ := func(method string, url string, body string, code int, res string) {
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code || (res != "" && rw.Body.String() != res) {
+			t.Fatalf("%s %s: %d %q", method, url, rw.Code, rw.Body.String())
+		}
+	}
+
+	tJ("PUT", "/items/7", `{"name":"box"}`, 200, `{"id":7,"name":"box"}`+"\n")
+	tJ("PUT", "/items/7", `{}`, 422, "")
+	tJ("PUT", "/items/7", `{"name":1}`, 400, "")
+	tJ("PUT", "/items/13", `{"name":"box"}`, 409, "")
+	tJ("POST", "/sum", `[1,2,3]`, 200, "6\n")
+	tJ("POST", "/items", `{"name":"bag"}`, 200, `{"id":1,"name":"bag"}`+"\n")
+	tJ("POST", "/items", `{}`, 422, "")
+	tJ("GET", "/bad", "", 500, "500 Internal Server Error")
+}
@@ -0,0 +1,286 @@
+package serv
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var regexpCache sync.Map
+
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	list := make([]string, len(e))
+	for i, ve := range e {
+		list[i] = ve.Error()
+	}
+	return strings.Join(list, "; ")
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+// parseRules splits a validate tag. A regex rule takes the rest of the tag,
+// so it may contain commas but has to be the last one.
+func parseRules(tag string) []rule {
+
+	var res []rule
+
+	for tag != "" {
+		var item string
+
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		r := rule{name: item}
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			r.name, r.param = item[:i], item[i+1:]
+		}
+
+		res = append(res, r)
+	}
+
+	return res
+}
+
+// Validate checks struct fields against their validate tags: required,
+// omitempty, min, max, len, regex, email and oneof. Nested structs, pointers
+// to structs and slices of structs are checked recursively.
+func Validate(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	var errs ValidationErrors
+
+	validateValue(rv, "", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "path", "query", "form", "header", "cookie"} {
+		if name := strings.Split(sf.Tag.Get(key), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func validateValue(v reflect.Value, path string, errs *ValidationErrors) {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			validateValue(v.Elem(), path, errs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+
+			sf := t.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous {
+				continue
+			}
+
+			tag := sf.Tag.Get("validate")
+			if tag == "-" {
+				continue
+			}
+
+			name := path
+			if !sf.Anonymous {
+				if name != "" {
+					name += "."
+				}
+				name += fieldName(sf)
+			}
+
+			fv := v.Field(i)
+
+			if validateField(fv, name, parseRules(tag), errs) {
+				validateValue(fv, name, errs)
+			}
+		}
+	}
+}
+
+// validateField applies rules to a single field and reports whether nested values should be checked.
+func validateField(v reflect.Value, name string, rules []rule, errs *ValidationErrors) bool {
+
+	zero := v.IsZero()
+
+	for _, r := range rules {
+		if r.name == "omitempty" && zero {
+			return false
+		}
+	}
+
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	for _, r := range rules {
+
+		if r.name == "omitempty" {
+			continue
+		}
+
+		if r.name == "required" {
+			if zero {
+				*errs = append(*errs, &ValidationError{Field: name, Rule: r.name, Message: "is required"})
+				return false
+			}
+			continue
+		}
+
+		if v.Kind() == reflect.Ptr {
+			continue
+		}
+
+		if msg := checkRule(v, r); msg != "" {
+			*errs = append(*errs, &ValidationError{Field: name, Rule: r.name, Param: r.param, Message: msg})
+		}
+	}
+
+	return true
+}
+
+func valueSize(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
+
+func checkRule(v reflect.Value, r rule) string {
+
+	switch r.name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(r.param, 64)
+		if err != nil {
+			return "invalid rule parameter " + r.param
+		}
+
+		size, isLen := valueSize(v)
+		if r.name == "len" {
+			isLen = true
+		}
+
+		what := "value"
+		if isLen {
+			what = "length"
+		}
+
+		switch {
+		case r.name == "min" && size < limit:
+			return what + " must be at least " + r.param
+		case r.name == "max" && size > limit:
+			return what + " must be at most " + r.param
+		case r.name == "len" && size != limit:
+			return "length must be " + r.param
+		}
+
+	case "regex":
+		re, err := compileRegexp(r.param)
+		if err != nil {
+			return "invalid rule parameter " + r.param
+		}
+		if v.Kind() != reflect.String || !re.MatchString(v.String()) {
+			return "must match " + r.param
+		}
+
+	case "email":
+		str := fmt.Sprint(v.Interface())
+		if addr, err := mail.ParseAddress(str); err != nil || addr.Address != str {
+			return "must be a valid email address"
+		}
+
+	case "oneof":
+		str := fmt.Sprint(v.Interface())
+		for _, item := range strings.Fields(r.param) {
+			if item == str {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(r.param), ", ")
+
+	default:
+		return "unknown rule " + r.name
+	}
+
+	return ""
+}
+
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+
+	if re, has := regexpCache.Load(expr); has {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	regexpCache.Store(expr, re)
+
+	return re, nil
+}
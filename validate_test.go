@@ -0,0 +1,111 @@
+package serv
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type validAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"omitempty,len=5,regex=^[0-9]{5}$"`
+}
+
+type validUser struct {
+	Name    string         `json:"name" validate:"required,min=2,max=10"`
+	Email   string         `json:"email" validate:"required,email"`
+	Age     int            `json:"age" validate:"min=18,max=150"`
+	Role    string         `json:"role" validate:"oneof=admin user"`
+	Tags    []string       `json:"tags" validate:"max=2"`
+	Home    *validAddress  `json:"home"`
+	Offices []validAddress `json:"offices"`
+}
+
+func TestValidate(t *testing.T) {
+
+	ok := &validUser{
+		Name:    "Bob",
+		Email:   "bob@example.com",
+		Age:     33,
+		Role:    "user",
+		Home:    &validAddress{City: "Moscow", Zip: "12345"},
+		Offices: []validAddress{{City: "Berlin"}},
+	}
+
+	if err := Validate(ok); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := &validUser{
+		Name:    "B",
+		Email:   "Bob <bob@example.com>",
+		Age:     10,
+		Role:    "root",
+		Tags:    []string{"a", "b", "c"},
+		Home:    &validAddress{Zip: "1234a"},
+		Offices: []validAddress{{City: "Berlin"}, {}},
+	}
+
+	errs, isVE := Validate(bad).(ValidationErrors)
+	if !isVE {
+		t.Fatal("ValidationErrors expected")
+	}
+
+	list := []string{}
+	for _, e := range errs {
+		list = append(list, e.Field+":"+e.Rule)
+	}
+
+	if strings.Join(list, ",") != "name:min,email:email,age:min,role:oneof,tags:max,home.city:required,home.zip:regex,offices[1].city:required" {
+		t.Fatalf("invalid errors: %v", list)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+
+	s := New()
+
+	s.Register("POST", "/user", func(c *Context) {
+		var u validUser
+		if err := c.BindValid(&u); err != nil {
+			c.WriteError(err)
+			return
+		}
+		c.WriteHeader(200)
+	})
+
+	tP := func(body string, code int, field string) {
+
+		req := httptest.NewRequest("POST", "/user", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		s.router.ServeHTTP(rw, req)
+
+		if rw.Code != code {
+			t.Fatalf("%s returns %d wait %d", body, rw.Code, code)
+		}
+
+		if code == 200 {
+			return
+		}
+
+		if rw.Header().Get("Content-Type") != "application/problem+json" {
+			t.Fatal("problem document expected")
+		}
+
+		var p struct {
+			Status int
+			Errors []map[string]string
+		}
+
+		if err := json.NewDecoder(rw.Body).Decode(&p); err != nil || p.Status != code || len(p.Errors) != 1 || p.Errors[0]["field"] != field {
+			t.Fatalf("invalid problem for %s: %+v", body, p)
+		}
+	}
+
+	tP(`{"name":"Bob","email":"bob@example.com","age":20,"role":"admin"}`, 200, "")
+	tP(`{"name":"Bob","email":"bob@example.com","age":"20","role":"admin"}`, 400, "age")
+	tP(`{"name":"Bob","email":"bob@example.com","age":20,"role":"guest"}`, 422, "role")
+}
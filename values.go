@@ -0,0 +1,194 @@
+package serv
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoValue     error = errors.New("value not found")
+	ErrInvalidUUID error = errors.New("invalid UUID")
+)
+
+type UUID [16]byte
+
+func ParseUUID(str string) (UUID, error) {
+
+	var u UUID
+
+	str = strings.TrimPrefix(strings.ToLower(str), "urn:uuid:")
+	if len(str) == 38 && str[0] == '{' && str[37] == '}' {
+		str = str[1:37]
+	}
+
+	if len(str) != 36 || str[8] != '-' || str[13] != '-' || str[18] != '-' || str[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+
+	src := str[0:8] + str[9:13] + str[14:18] + str[19:23] + str[24:]
+
+	if _, err := hex.Decode(u[:], []byte(src)); err != nil {
+		return u, ErrInvalidUUID
+	}
+
+	return u, nil
+}
+
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(data []byte) error {
+	v, err := ParseUUID(string(data))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+func intValue(str string, has bool) (int, error) {
+	if !has {
+		return 0, ErrNoValue
+	}
+	return strconv.Atoi(str)
+}
+
+func int64Value(str string, has bool) (int64, error) {
+	if !has {
+		return 0, ErrNoValue
+	}
+	return strconv.ParseInt(str, 10, 64)
+}
+
+func floatValue(str string, has bool) (float64, error) {
+	if !has {
+		return 0, ErrNoValue
+	}
+	return strconv.ParseFloat(str, 64)
+}
+
+func boolValue(str string, has bool) (bool, error) {
+	if !has {
+		return false, ErrNoValue
+	}
+	return strconv.ParseBool(str)
+}
+
+func durationValue(str string, has bool) (time.Duration, error) {
+	if !has {
+		return 0, ErrNoValue
+	}
+	return time.ParseDuration(str)
+}
+
+func uuidValue(str string, has bool) (UUID, error) {
+	if !has {
+		return UUID{}, ErrNoValue
+	}
+	return ParseUUID(str)
+}
+
+func timeValue(layout string, str string, has bool) (time.Time, error) {
+	if !has {
+		return time.Time{}, ErrNoValue
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return time.Parse(layout, str)
+}
+
+// ValueGetter provides error-returning and default-value getters on top of
+// a lookup function. ParamValues and QueryValues embed it.
+type ValueGetter struct {
+	lookup func(name string) (string, bool)
+}
+
+func (g ValueGetter) ParseInt(name string) (int, error) {
+	return intValue(g.lookup(name))
+}
+
+func (g ValueGetter) ParseInt64(name string) (int64, error) {
+	return int64Value(g.lookup(name))
+}
+
+func (g ValueGetter) ParseFloat(name string) (float64, error) {
+	return floatValue(g.lookup(name))
+}
+
+func (g ValueGetter) ParseBool(name string) (bool, error) {
+	return boolValue(g.lookup(name))
+}
+
+func (g ValueGetter) ParseDuration(name string) (time.Duration, error) {
+	return durationValue(g.lookup(name))
+}
+
+func (g ValueGetter) ParseUUID(name string) (UUID, error) {
+	return uuidValue(g.lookup(name))
+}
+
+func (g ValueGetter) ParseTime(name string, layout string) (time.Time, error) {
+	v, h := g.lookup(name)
+	return timeValue(layout, v, h)
+}
+
+func (g ValueGetter) GetStringOr(name string, def string) string {
+	if v, h := g.lookup(name); h {
+		return v
+	}
+	return def
+}
+
+func (g ValueGetter) GetIntOr(name string, def int) int {
+	if v, err := g.ParseInt(name); err == nil {
+		return v
+	}
+	return def
+}
+
+func (g ValueGetter) GetInt64Or(name string, def int64) int64 {
+	if v, err := g.ParseInt64(name); err == nil {
+		return v
+	}
+	return def
+}
+
+func (g ValueGetter) GetFloatOr(name string, def float64) float64 {
+	if v, err := g.ParseFloat(name); err == nil {
+		return v
+	}
+	return def
+}
+
+func (g ValueGetter) GetBoolOr(name string, def bool) bool {
+	if v, err := g.ParseBool(name); err == nil {
+		return v
+	}
+	return def
+}
+
+func (g ValueGetter) GetDurationOr(name string, def time.Duration) time.Duration {
+	if v, err := g.ParseDuration(name); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,464 @@
+package serv
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	WSText   = 1
+	WSBinary = 2
+
+	wsContinuation = 0
+	wsClose        = 8
+	wsPing         = 9
+	wsPong         = 10
+
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+const (
+	WSCloseNormal          = 1000
+	WSCloseGoingAway       = 1001
+	WSCloseProtocolError   = 1002
+	WSCloseUnsupportedData = 1003
+	WSCloseNoStatus        = 1005
+	WSCloseInvalidPayload  = 1007
+	WSClosePolicyViolation = 1008
+	WSCloseTooBig          = 1009
+	WSCloseInternalError   = 1011
+)
+
+var (
+	ErrWSClosed    error = errors.New("websocket closed")
+	ErrWSHandshake error = errors.New("invalid websocket handshake")
+	ErrWSOrigin    error = errors.New("websocket origin not allowed")
+)
+
+type WSHandler func(ws *WSConn)
+
+// WSOptions configures websocket endpoints. With no AllowedOrigins and no
+// CheckOrigin only requests without Origin or from the same host are accepted,
+// "*" allows any origin. PingInterval enables pings and drops connections
+// that stay silent for two intervals.
+type WSOptions struct {
+	MaxMessageSize int64
+	AllowedOrigins []string
+	CheckOrigin    func(c *Context) bool
+	PingInterval   time.Duration
+	Subprotocols   []string
+}
+
+type WSCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WSCloseError) Error() string {
+	return "websocket closed: " + strconv.Itoa(e.Code) + " " + e.Reason
+}
+
+type WSConn struct {
+	c           *Context
+	conn        net.Conn
+	br          *bufio.Reader
+	opts        WSOptions
+	wmu         sync.Mutex
+	closeSent   bool
+	done        chan struct{}
+	once        sync.Once
+	Subprotocol string
+}
+
+func headerHasToken(h http.Header, name string, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (opts *WSOptions) originAllowed(c *Context) bool {
+
+	if opts.CheckOrigin != nil {
+		return opts.CheckOrigin(c)
+	}
+
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	if len(opts.AllowedOrigins) > 0 {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, c.req.Host)
+}
+
+// UpgradeWebSocket performs the RFC 6455 handshake. On failure it replies
+// with 400, 403 or 426 and returns an error.
+func (c *Context) UpgradeWebSocket(opts *WSOptions) (*WSConn, error) {
+
+	ws := &WSConn{c: c, done: make(chan struct{})}
+	if opts != nil {
+		ws.opts = *opts
+	}
+	if ws.opts.MaxMessageSize <= 0 {
+		ws.opts.MaxMessageSize = 1 << 20
+	}
+
+	if c.Method() != "GET" || !headerHasToken(c.req.Header, "Connection", "upgrade") || !headerHasToken(c.req.Header, "Upgrade", "websocket") {
+		c.StandardError(400)
+		return nil, ErrWSHandshake
+	}
+
+	if c.GetHeader("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		c.StandardError(426)
+		return nil, ErrWSHandshake
+	}
+
+	key := c.GetHeader("Sec-WebSocket-Key")
+	if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 16 {
+		c.StandardError(400)
+		return nil, ErrWSHandshake
+	}
+
+	if !ws.opts.originAllowed(c) {
+		c.StandardError(403)
+		return nil, ErrWSOrigin
+	}
+
+	for _, p := range ws.opts.Subprotocols {
+		if headerHasToken(c.req.Header, "Sec-WebSocket-Protocol", p) {
+			ws.Subprotocol = p
+			break
+		}
+	}
+
+	conn, brw, err := c.rw.Hijack()
+	if err != nil {
+		c.StandardError(500)
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+	if ws.Subprotocol != "" {
+		resp += "Sec-WebSocket-Protocol: " + ws.Subprotocol + "\r\n"
+	}
+	resp += "\r\n"
+
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws.conn = conn
+	ws.br = brw.Reader
+
+	if ws.opts.PingInterval > 0 {
+		go ws.pinger()
+	}
+
+	return ws, nil
+}
+
+func (ws *WSConn) Context() *Context {
+	return ws.c
+}
+
+func (ws *WSConn) pinger() {
+	ticker := time.NewTicker(ws.opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.done:
+			return
+		case <-ticker.C:
+			if ws.Ping(nil) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (ws *WSConn) writeFrame(op int, data []byte) error {
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+
+	if ws.closeSent {
+		return ErrWSClosed
+	}
+
+	if op == wsClose {
+		ws.closeSent = true
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | byte(op)
+
+	switch n := len(data); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if _, err := ws.conn.Write(append(header, data...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ws *WSConn) WriteMessage(typ int, data []byte) error {
+	if typ != WSText && typ != WSBinary {
+		return errors.New("invalid websocket message type")
+	}
+	return ws.writeFrame(typ, data)
+}
+
+func (ws *WSConn) WriteText(text string) error {
+	return ws.writeFrame(WSText, []byte(text))
+}
+
+func (ws *WSConn) Ping(data []byte) error {
+	return ws.writeFrame(wsPing, data)
+}
+
+// CloseWith sends a close frame with code and reason and closes the connection.
+func (ws *WSConn) CloseWith(code int, reason string) error {
+
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+
+	err := ws.writeFrame(wsClose, payload)
+
+	ws.shutdown()
+
+	if err == ErrWSClosed {
+		return nil
+	}
+
+	return err
+}
+
+func (ws *WSConn) Close() error {
+	return ws.CloseWith(WSCloseNormal, "")
+}
+
+func (ws *WSConn) shutdown() {
+	ws.once.Do(func() {
+		close(ws.done)
+		ws.conn.Close()
+	})
+}
+
+func (ws *WSConn) fail(code int, reason string) error {
+	ws.CloseWith(code, reason)
+	return &WSCloseError{Code: code, Reason: reason}
+}
+
+type wsFrame struct {
+	fin     bool
+	op      int
+	payload []byte
+}
+
+func (ws *WSConn) readFrame(limit int64) (*wsFrame, error) {
+
+	if ws.opts.PingInterval > 0 {
+		ws.conn.SetReadDeadline(time.Now().Add(2 * ws.opts.PingInterval))
+	}
+
+	var head [2]byte
+	if _, err := io.ReadFull(ws.br, head[:]); err != nil {
+		return nil, err
+	}
+
+	f := &wsFrame{fin: head[0]&0x80 != 0, op: int(head[0] & 0x0f)}
+
+	if head[0]&0x70 != 0 {
+		return nil, ws.fail(WSCloseProtocolError, "reserved bits set")
+	}
+
+	if head[1]&0x80 == 0 {
+		return nil, ws.fail(WSCloseProtocolError, "frame not masked")
+	}
+
+	size := int64(head[1] & 0x7f)
+
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return nil, err
+		}
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return nil, err
+		}
+		if ext[0]&0x80 != 0 {
+			return nil, ws.fail(WSCloseProtocolError, "invalid frame length")
+		}
+		size = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if f.op >= wsClose && (size > 125 || !f.fin) {
+		return nil, ws.fail(WSCloseProtocolError, "invalid control frame")
+	}
+
+	if size > limit {
+		return nil, ws.fail(WSCloseTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return nil, err
+	}
+
+	f.payload = make([]byte, size)
+	if _, err := io.ReadFull(ws.br, f.payload); err != nil {
+		return nil, err
+	}
+
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+
+	return f, nil
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011, code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// automatically. When the peer closes the connection a *WSCloseError is returned.
+func (ws *WSConn) ReadMessage() (int, []byte, error) {
+
+	typ := 0
+	var msg []byte
+
+	for {
+		limit := ws.opts.MaxMessageSize - int64(len(msg))
+		if limit < 125 {
+			limit = 125
+		}
+
+		f, err := ws.readFrame(limit)
+		if err != nil {
+			ws.shutdown()
+			return 0, nil, err
+		}
+
+		switch f.op {
+		case wsPing:
+			if err := ws.writeFrame(wsPong, f.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+
+		case wsPong:
+			continue
+
+		case wsClose:
+			code, reason := WSCloseNoStatus, ""
+			if len(f.payload) == 1 {
+				return 0, nil, ws.fail(WSCloseProtocolError, "invalid close frame")
+			}
+			if len(f.payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(f.payload))
+				reason = string(f.payload[2:])
+				if !validCloseCode(code) || !utf8.ValidString(reason) {
+					return 0, nil, ws.fail(WSCloseProtocolError, "invalid close frame")
+				}
+			}
+			reply := code
+			if code == WSCloseNoStatus {
+				reply = WSCloseNormal
+			}
+			ws.CloseWith(reply, "")
+			return 0, nil, &WSCloseError{Code: code, Reason: reason}
+
+		case WSText, WSBinary:
+			if typ != 0 {
+				return 0, nil, ws.fail(WSCloseProtocolError, "unexpected data frame")
+			}
+			typ = f.op
+
+		case wsContinuation:
+			if typ == 0 {
+				return 0, nil, ws.fail(WSCloseProtocolError, "unexpected continuation frame")
+			}
+
+		default:
+			return 0, nil, ws.fail(WSCloseProtocolError, "unknown opcode")
+		}
+
+		msg = append(msg, f.payload...)
+
+		if int64(len(msg)) > ws.opts.MaxMessageSize {
+			return 0, nil, ws.fail(WSCloseTooBig, "message too big")
+		}
+
+		if f.fin {
+			if typ == WSText && !utf8.Valid(msg) {
+				return 0, nil, ws.fail(WSCloseInvalidPayload, "invalid utf-8")
+			}
+			return typ, msg, nil
+		}
+	}
+}
+
+func (s *Server) wsHandler(fn WSHandler) Handler {
+	return func(c *Context) {
+		ws, err := c.UpgradeWebSocket(s.router.wsOptions)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		fn(ws)
+	}
+}
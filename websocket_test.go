@@ -0,0 +1,181 @@
+package serv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wsTestClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func wsDial(t *testing.T, addr string, path string, headers map[string]string) (*wsTestClient, *http.Response) {
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	for k, v := range headers {
+		req += k + ": " + v + "\r\n"
+	}
+	req += "\r\n"
+
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &wsTestClient{conn: conn, br: br}, res
+}
+
+func (wc *wsTestClient) send(op byte, fin bool, data []byte) {
+
+	head := []byte{op, 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+
+	switch n := len(data); {
+	case n < 126:
+		head[1] |= byte(n)
+	default:
+		head[1] |= 126
+		head = append(head, 0, 0)
+		binary.BigEndian.PutUint16(head[2:], uint16(n))
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	payload := make([]byte, len(data))
+	for i := range data {
+		payload[i] = data[i] ^ mask[i%4]
+	}
+
+	wc.conn.Write(append(append(head, mask...), payload...))
+}
+
+func (wc *wsTestClient) recv(t *testing.T) (byte, []byte) {
+
+	var head [2]byte
+	if _, err := io.ReadFull(wc.br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	n := int(head[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(wc.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(wc.br, data); err != nil {
+		t.Fatal(err)
+	}
+
+	return head[0] & 0x0f, data
+}
+
+func TestWebSocket(t *testing.T) {
+
+	s := New()
+	s.SetWebSocketOptions(&WSOptions{MaxMessageSize: 16})
+	s.SetAuthCheck(func(login, passwd string) bool { return login == "admin" && passwd == "secret" })
+
+	echo := func(ws *WSConn) {
+		for {
+			typ, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			ws.WriteMessage(typ, msg)
+		}
+	}
+
+	s.WebSocket("/ws", echo)
+	s.WebSocketAuth("/admin/ws", echo)
+
+	ts := httptest.NewServer(s.router)
+	defer ts.Close()
+
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	wc, res := wsDial(t, addr, "/ws", nil)
+	defer wc.conn.Close()
+
+	if res.StatusCode != 101 || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("invalid handshake: %d %v", res.StatusCode, res.Header)
+	}
+
+	wc.send(WSText, true, []byte("hello"))
+	if op, data := wc.recv(t); op != WSText || string(data) != "hello" {
+		t.Fatalf("invalid echo: %d %q", op, data)
+	}
+
+	wc.send(WSBinary, false, []byte("ab"))
+	wc.send(wsPing, true, []byte("p"))
+	wc.send(wsContinuation, true, []byte("cd"))
+
+	if op, data := wc.recv(t); op != wsPong || string(data) != "p" {
+		t.Fatalf("invalid pong: %d %q", op, data)
+	}
+
+	if op, data := wc.recv(t); op != WSBinary || string(data) != "abcd" {
+		t.Fatalf("invalid fragmented echo: %d %q", op, data)
+	}
+
+	wc.send(wsClose, true, []byte{0x03, 0xe8})
+	if op, data := wc.recv(t); op != wsClose || binary.BigEndian.Uint16(data) != WSCloseNormal {
+		t.Fatalf("invalid close: %d %v", op, data)
+	}
+
+	wc, _ = wsDial(t, addr, "/ws", nil)
+	defer wc.conn.Close()
+
+	wc.send(WSText, true, []byte(strings.Repeat("x", 200)))
+	if op, data := wc.recv(t); op != wsClose || binary.BigEndian.Uint16(data) != WSCloseTooBig {
+		t.Fatalf("invalid close for big message: %d %v", op, data)
+	}
+
+	wc, _ = wsDial(t, addr, "/ws", nil)
+	defer wc.conn.Close()
+
+	wc.send(WSText, true, []byte{0xff, 0xfe})
+	if op, data := wc.recv(t); op != wsClose || binary.BigEndian.Uint16(data) != WSCloseInvalidPayload {
+		t.Fatalf("invalid close for bad utf-8: %d %v", op, data)
+	}
+
+	if _, res := wsDial(t, addr, "/ws", map[string]string{"Origin": "http://evil.example.com"}); res.StatusCode != 403 {
+		t.Fatalf("foreign origin accepted: %d", res.StatusCode)
+	}
+
+	if _, res := wsDial(t, addr, "/ws", map[string]string{"Origin": "http://" + addr}); res.StatusCode != 101 {
+		t.Fatalf("same origin rejected: %d", res.StatusCode)
+	}
+
+	if _, res := wsDial(t, addr, "/admin/ws", nil); res.StatusCode != 401 {
+		t.Fatalf("unauthorized websocket accepted: %d", res.StatusCode)
+	}
+
+	if _, res := wsDial(t, addr, "/admin/ws", map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0"}); res.StatusCode != 101 {
+		t.Fatalf("authorized websocket rejected: %d", res.StatusCode)
+	}
+}